@@ -1,8 +1,14 @@
 package main
 
 import (
+	"log"
+	"os"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/oauth"
 	"github.com/achyar10/go-auth/src/app/user"
 	"github.com/achyar10/go-auth/src/config"
+	"github.com/achyar10/go-auth/src/helper"
 	"github.com/achyar10/go-auth/src/routes"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
@@ -10,9 +16,34 @@ import (
 )
 
 func main() {
+	// Perintah CLI untuk rotasi kunci JWT: `go run . rotate-keys`
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		key, err := helper.RotateSigningKey()
+		if err != nil {
+			log.Fatal("Gagal merotasi kunci JWT:", err)
+		}
+		log.Println("Kunci JWT baru aktif:", key.Kid)
+		return
+	}
+
 	// Koneksi ke database
 	db := config.ConnectDatabase()
 
+	// Muat kunci penandatangan JWT
+	if err := helper.SetupSigningKey(); err != nil {
+		log.Fatal("Gagal memuat kunci JWT:", err)
+	}
+
+	// Kunci enkripsi data sensitif (secret TOTP)
+	if err := helper.SetupEncryptionKey(); err != nil {
+		log.Fatal("Gagal memuat kunci enkripsi:", err)
+	}
+
+	// Store rate limit (memory atau redis)
+	if _, err := helper.SetupRateLimitStore(); err != nil {
+		log.Fatal("Gagal menyiapkan rate limit:", err)
+	}
+
 	// Inisialisasi Fiber
 	app := fiber.New()
 
@@ -25,6 +56,25 @@ func main() {
 	routes.SetupRoutes(app, db)
 
 	// Jalankan server di port 3000
-	db.AutoMigrate(&user.User{})
+	db.AutoMigrate(
+		&user.User{},
+		&user.RecoveryCode{},
+		&user.WebAuthnCredential{},
+		&user.UserIdentity{},
+		&user.PersonalAccessToken{},
+		&auth.RefreshToken{},
+		&auth.OneTimeToken{},
+		&auth.AuditLog{},
+		&auth.LoginFailure{},
+		&oauth.Client{},
+		&oauth.AuthorizationCode{},
+		&oauth.DeviceCode{},
+		&helper.RevokedToken{},
+		&helper.UserTokenRevocation{},
+	)
+
+	// Daftar pencabutan token (logout)
+	helper.SetupRevocationStore(db)
+
 	app.Listen(":3000")
 }
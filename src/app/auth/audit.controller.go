@@ -0,0 +1,16 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type AuditController struct {
+	Service AuditService
+}
+
+func NewAuditController(service AuditService) *AuditController {
+	return &AuditController{Service: service}
+}
+
+func (ac *AuditController) List(ctx *fiber.Ctx) error {
+	response := ac.Service.List(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// Event audit log
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+)
+
+// AuditService interface
+type AuditService interface {
+	List(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// AuditServiceImpl struct
+type AuditServiceImpl struct {
+	DB *gorm.DB
+}
+
+// Konstruktor untuk AuditService
+func NewAuditService(db *gorm.DB) AuditService {
+	return &AuditServiceImpl{DB: db}
+}
+
+// Implementasi List (daftar audit log, bisa difilter ?event=, ?actor_id=, ?user_id=)
+func (a *AuditServiceImpl) List(ctx *fiber.Ctx) utility.APIResponse {
+	var logs []AuditLog
+
+	query := helper.ParseQueryParams(ctx)
+	if ctx.Query("sort_by") == "" {
+		query.SortBy, query.Order = "id", "DESC"
+	}
+
+	// Hanya kolom ini yang boleh dipakai sebagai filter
+	for field := range query.Filters {
+		if field != "event" && field != "actor_id" && field != "user_id" {
+			delete(query.Filters, field)
+		}
+	}
+
+	paginatedResult := helper.ApplyFiltersAndPagination(a.DB, &logs, query, []string{"event"})
+	metadata := helper.GenerateMetadata(query, paginatedResult.TotalCount, paginatedResult.PageCount)
+
+	return utility.SuccessResponse(http.StatusOK, "OK", map[string]interface{}{
+		"records":  paginatedResult.Records,
+		"metadata": metadata,
+	})
+}
+
+// RecordAudit menyimpan audit log; kegagalan hanya dicatat ke log agar tidak membatalkan aksi utama
+func RecordAudit(db *gorm.DB, ctx *fiber.Ctx, event string, actorID *int64, userID *int64, details map[string]interface{}) {
+	entry := AuditLog{
+		Event:     event,
+		ActorId:   actorID,
+		UserId:    userID,
+		IpAddress: ctx.IP(),
+		UserAgent: truncate(ctx.Get(fiber.HeaderUserAgent), 255),
+	}
+	if len(details) > 0 {
+		encoded, _ := json.Marshal(details)
+		detailsJSON := string(encoded)
+		entry.Details = &detailsJSON
+	}
+
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("Gagal menyimpan audit log %s: %v", event, err)
+	}
+}
+
+// truncate memotong string agar muat di kolom database
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
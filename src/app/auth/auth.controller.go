@@ -1,6 +1,9 @@
 package auth
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/gofiber/fiber/v2"
+)
 
 type AuthController struct {
 	Service AuthService
@@ -20,7 +23,41 @@ func (ac *AuthController) Login(ctx *fiber.Ctx) error {
 	return ctx.Status(response.Status).JSON(response)
 }
 
+func (ac *AuthController) RotateRefreshToken(ctx *fiber.Ctx) error {
+	response := ac.Service.RotateRefreshToken(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// RefreshToken alias lama GET /auth/refresh (deprecated, dihapus di rilis berikutnya).
+// Sekarang sama dengan RotateRefreshToken: wajib mengirim refresh_token, access token saja tidak cukup.
 func (ac *AuthController) RefreshToken(ctx *fiber.Ctx) error {
-	response := ac.Service.RefreshToken(ctx)
+	ctx.Set("Deprecation", "true")
+	ctx.Set(fiber.HeaderLink, `</auth/token/refresh>; rel="successor-version"`)
+	return ac.RotateRefreshToken(ctx)
+}
+
+func (ac *AuthController) Logout(ctx *fiber.Ctx) error {
+	response := ac.Service.Logout(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (ac *AuthController) LogoutUser(ctx *fiber.Ctx) error {
+	response := ac.Service.LogoutUser(ctx)
 	return ctx.Status(response.Status).JSON(response)
 }
+
+func (ac *AuthController) ListKeys(ctx *fiber.Ctx) error {
+	response := ac.Service.ListKeys(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (ac *AuthController) RotateKeys(ctx *fiber.Ctx) error {
+	response := ac.Service.RotateKeys(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// JWKS mempublikasikan public key untuk verifikasi JWT oleh service lain
+func (ac *AuthController) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(helper.GetJWKS())
+}
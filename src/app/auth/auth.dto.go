@@ -2,8 +2,9 @@ package auth
 
 type RegisterDTO struct {
 	Username string `json:"username" validate:"required,min=3,max=100"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"` // Aturan lain lewat helper.ValidatePassword
 	Fullname string `json:"fullname"`
+	Email    string `json:"email" validate:"omitempty,email,max=255"` // Wajib jika REQUIRE_EMAIL_VERIFICATION aktif
 	Role     string `json:"role" validate:"oneof=admin user"`
 }
 
@@ -11,3 +12,16 @@ type LoginDTO struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutDTO struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+type ImpersonateDTO struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
@@ -1,9 +1,79 @@
 package auth
 
+import "time"
+
 type LoginResponse struct {
-	Id       int64  `json:"user_id"`
-	Username string `json:"username"`
-	Fullname string `json:"fullname"`
-	Role     string `json:"role"`
-	Token    string `json:"access_token"`
+	Id           int64  `json:"user_id"`
+	Username     string `json:"username"`
+	Fullname     string `json:"fullname"`
+	Role         string `json:"role"`
+	Token        string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// MFAChallengeResponse dikembalikan oleh Login jika user mengaktifkan MFA
+type MFAChallengeResponse struct {
+	MFARequired bool     `json:"mfa_required"`
+	MFAToken    string   `json:"mfa_token"`
+	Methods     []string `json:"methods"`
+	ExpiresIn   int      `json:"expires_in"`
+}
+
+// RefreshToken menyimpan refresh token opaque dalam bentuk hash.
+// Setiap rotasi membuat token baru dengan FamilyId yang sama, sehingga
+// pemakaian ulang token lama bisa dideteksi dan seluruh family dicabut.
+type RefreshToken struct {
+	Id        int64      `gorm:"primaryKey" json:"id"`
+	UserId    int64      `gorm:"not null;index" json:"user_id"`
+	FamilyId  string     `gorm:"type:varchar(36);not null;index" json:"family_id"`
+	ClientId  *string    `gorm:"type:varchar(64);null;index" json:"client_id"` // Kosong untuk sesi first-party (/auth/login)
+	Scope     *string    `gorm:"type:varchar(255);null" json:"scope"`
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:datetime;not null" json:"expires_at"`
+	RotatedAt *time.Time `gorm:"type:datetime;null" json:"rotated_at"`
+	RevokedAt *time.Time `gorm:"type:datetime;null" json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// OneTimeToken adalah token sekali pakai berumur pendek (misal reset password) yang disimpan dalam bentuk hash
+type OneTimeToken struct {
+	Id        int64      `gorm:"primaryKey" json:"id"`
+	UserId    int64      `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"type:varchar(30);not null;index" json:"purpose"`
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Payload   *string    `gorm:"type:varchar(255);null" json:"-"` // Data yang diikat ke token, misal email yang diverifikasi
+	ExpiresAt time.Time  `gorm:"type:datetime;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"type:datetime;null" json:"used_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// AuditLog mencatat kejadian penting terkait keamanan (misal impersonasi) untuk ditelusuri admin
+type AuditLog struct {
+	Id        int64     `gorm:"primaryKey" json:"id"`
+	Event     string    `gorm:"type:varchar(50);not null;index" json:"event"`
+	ActorId   *int64    `gorm:"index;null" json:"actor_id"` // User yang melakukan aksi
+	UserId    *int64    `gorm:"index;null" json:"user_id"`  // User yang terdampak
+	IpAddress string    `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent"`
+	Details   *string   `gorm:"type:text;null" json:"details"` // JSON tambahan sesuai event
+	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// ImpersonationResponse dikembalikan saat admin mulai impersonasi
+type ImpersonationResponse struct {
+	Token     string `json:"access_token"`
+	TokenType string `json:"token_type"`
+	ExpiresIn int    `json:"expires_in"`
+	UserId    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	ActorId   int64  `json:"actor_id"`
+}
+
+// LoginFailure menghitung login gagal per alamat IP, dipakai bersama penghitung per user untuk backoff / lockout
+type LoginFailure struct {
+	Ip           string     `gorm:"type:varchar(45);primaryKey" json:"ip"`
+	FailedLogins int        `gorm:"not null;default:0" json:"failed_logins"`
+	LastFailedAt *time.Time `gorm:"type:datetime;null" json:"last_failed_at"`
+	LockedUntil  *time.Time `gorm:"type:datetime;null" json:"locked_until"`
 }
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"log"
+
+	"github.com/achyar10/go-auth/src/helper"
 	"github.com/achyar10/go-auth/src/middleware"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -8,11 +11,74 @@ import (
 
 // SetupAuthRoutes mengatur routing untuk authentication
 func SetupAuthRoutes(app *fiber.App, db *gorm.DB) {
-	authService := NewAuthService(db)
+	if err := SetupCredentialBackends(); err != nil {
+		log.Fatal("Konfigurasi backend autentikasi tidak valid:", err)
+	}
+
+	mailer := helper.NewMailer()
+	authService := NewAuthService(db, mailer)
 	authController := NewAuthController(authService)
+	mfaService := NewMFAService(db)
+	mfaController := NewMFAController(mfaService)
+	passwordService := NewPasswordService(db, mailer)
+	passwordController := NewPasswordController(passwordService)
+	emailService := NewEmailService(db, mailer)
+	emailController := NewEmailController(emailService)
+	magicLinkService := NewMagicLinkService(db, mailer)
+	magicLinkController := NewMagicLinkController(magicLinkService)
+	impersonationService := NewImpersonationService(db)
+	impersonationController := NewImpersonationController(impersonationService)
+	auditService := NewAuditService(db)
+	auditController := NewAuditController(auditService)
+
+	app.Get("/.well-known/jwks.json", authController.JWKS)
 
 	authRoutes := app.Group("/auth")
-	authRoutes.Post("/register", authController.Register)
-	authRoutes.Post("/login", middleware.BasicAuthMiddleware, authController.Login)
-	authRoutes.Get("/refresh", middleware.AuthMiddleware, authController.RefreshToken)
+	// Rate limit per IP, username dan client (bisa diatur lewat env RATE_LIMIT_<NAMA>)
+	authRoutes.Post("/register",
+		middleware.RateLimit("register_ip", "10/1h", middleware.RateLimitByIP),
+		middleware.RateLimit("register_username", "3/1h", middleware.RateLimitByUsername),
+		authController.Register)
+	authRoutes.Post("/login",
+		middleware.RateLimit("login_ip", "30/1m", middleware.RateLimitByIP),
+		middleware.BasicAuthMiddleware,
+		middleware.RateLimit("login_username", "10/1m", middleware.RateLimitByUsername),
+		authController.Login)
+	refreshLimits := []fiber.Handler{
+		middleware.RateLimit("token_refresh_ip", "120/1m", middleware.RateLimitByIP),
+		middleware.RateLimit("token_refresh_client", "20/1m", RateLimitByRefreshClient(db)),
+		middleware.RateLimit("token_refresh_family", "10/1m", RateLimitByRefreshFamily(db)),
+	}
+	authRoutes.Post("/token/refresh", append(refreshLimits, authController.RotateRefreshToken)...)
+	// Alias lama (deprecated) untuk satu rilis, dengan aturan yang sama seperti POST /auth/token/refresh
+	authRoutes.Get("/refresh", append(refreshLimits, authController.RefreshToken)...)
+	authRoutes.Post("/logout", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, authController.Logout)
+	authRoutes.Post("/users/:id/logout", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, middleware.RoleMiddleware("admin"), authController.LogoutUser)
+	authRoutes.Get("/keys", middleware.AuthMiddleware, middleware.RoleMiddleware("admin"), authController.ListKeys)
+	authRoutes.Post("/keys/rotate", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, middleware.RoleMiddleware("admin"), authController.RotateKeys)
+	authRoutes.Get("/audit-logs", middleware.AuthMiddleware, middleware.RoleMiddleware("admin"), auditController.List)
+
+	// Impersonasi user oleh admin (support)
+	authRoutes.Post("/impersonate/stop", middleware.AuthMiddleware, middleware.RequireUser, impersonationController.Stop)
+	authRoutes.Post("/impersonate/:id", middleware.AuthMiddleware, middleware.RequireUser, middleware.RoleMiddleware("admin"), impersonationController.Start)
+
+	// Reset password mandiri
+	authRoutes.Post("/password/forgot", passwordController.Forgot)
+	authRoutes.Post("/password/reset", passwordController.Reset)
+
+	// Verifikasi email
+	authRoutes.Get("/email/verify", emailController.Verify)
+	authRoutes.Post("/email/verify/resend", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, emailController.ResendVerification)
+
+	// Login tanpa password lewat link email
+	authRoutes.Post("/magic-link", magicLinkController.Send)
+	authRoutes.Get("/magic-link/verify", magicLinkController.Verify)
+
+	// Multi-factor authentication (TOTP & kode pemulihan)
+	mfaRoutes := authRoutes.Group("/mfa")
+	mfaRoutes.Post("/verify", middleware.RateLimit("mfa_verify_ip", "30/1m", middleware.RateLimitByIP), mfaController.Verify)
+	mfaRoutes.Post("/totp/enroll", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, mfaController.EnrollTOTP)
+	mfaRoutes.Post("/totp/confirm", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, mfaController.ConfirmTOTP)
+	mfaRoutes.Delete("/totp", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, mfaController.DisableTOTP)
+	mfaRoutes.Post("/recovery-codes", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, mfaController.RegenerateRecoveryCodes)
 }
@@ -1,12 +1,14 @@
 package auth
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 
 	"github.com/achyar10/go-auth/src/app/user"
@@ -14,24 +16,35 @@ import (
 	"github.com/achyar10/go-auth/src/utility"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("username or password wrong")
+	ErrEmailNotVerified   = errors.New("email address has not been verified")
+)
+
 // AuthService interface
 type AuthService interface {
 	Register(ctx *fiber.Ctx) utility.APIResponse
 	Login(ctx *fiber.Ctx) utility.APIResponse
-	RefreshToken(ctx *fiber.Ctx) utility.APIResponse
+	RotateRefreshToken(ctx *fiber.Ctx) utility.APIResponse
+	Logout(ctx *fiber.Ctx) utility.APIResponse
+	LogoutUser(ctx *fiber.Ctx) utility.APIResponse
+	ListKeys(ctx *fiber.Ctx) utility.APIResponse
+	RotateKeys(ctx *fiber.Ctx) utility.APIResponse
 }
 
 // AuthServiceImpl struct
 type AuthServiceImpl struct {
 	DB       *gorm.DB
 	Validate *validator.Validate
+	Mailer   helper.Mailer
 }
 
 // Konstruktor untuk AuthService
-func NewAuthService(db *gorm.DB) AuthService {
+func NewAuthService(db *gorm.DB, mailer helper.Mailer) AuthService {
 	return &AuthServiceImpl{
 		DB:       db,
 		Validate: validator.New(),
+		Mailer:   mailer,
 	}
 }
 
@@ -49,14 +62,42 @@ func (a *AuthServiceImpl) Register(ctx *fiber.Ctx) utility.APIResponse {
 		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
 	}
 
+	// Validasi password terhadap password policy
+	if errs := helper.ValidatePassword(dto.Password, dto.Username, dto.Fullname, dto.Email); len(errs) > 0 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", errs)
+	}
+
+	// Email opsional, kecuali login mewajibkan email terverifikasi
+	var email *string
+	if dto.Email != "" {
+		email = &dto.Email
+	} else if helper.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", false) {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"email wajib diisi"})
+	}
+
+	// Email harus unik
+	if email != nil {
+		taken, err := user.EmailTaken(a.DB, *email, 0)
+		if err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+		}
+		if taken {
+			return utility.ErrorResponse(http.StatusConflict, "Email is already registered", nil)
+		}
+	}
+
 	// Hash password
-	hashedPassword := helper.HashPassword(dto.Password)
+	hashedPassword, err := helper.HashPassword(dto.Password)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+	}
 
 	// Simpan user baru
 	newUser := user.User{
 		Username: dto.Username,
 		Password: &hashedPassword,
 		Fullname: &dto.Fullname,
+		Email:    email,
 		Role:     user.Role(dto.Role),
 		IsActive: true,
 	}
@@ -65,13 +106,17 @@ func (a *AuthServiceImpl) Register(ctx *fiber.Ctx) utility.APIResponse {
 		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
 	}
 
+	// Kirim link verifikasi email; kegagalan tidak membatalkan registrasi (bisa dikirim ulang)
+	if err := sendVerificationEmail(a.DB, a.Mailer, newUser); err != nil {
+		log.Println("Gagal membuat token verifikasi email:", err)
+	}
+
 	return utility.SuccessResponse(http.StatusCreated, "User created successfully", newUser)
 }
 
 // Implementasi Login User
 func (a *AuthServiceImpl) Login(ctx *fiber.Ctx) utility.APIResponse {
 	var dto LoginDTO
-	var foundUser user.User
 
 	username := ctx.Locals("username").(string)
 	password := ctx.Locals("password").(string)
@@ -90,55 +135,126 @@ func (a *AuthServiceImpl) Login(ctx *fiber.Ctx) utility.APIResponse {
 		return utility.ErrorResponse(http.StatusUnauthorized, "Request body does not match Basic Auth credentials", nil)
 	}
 
-	// Cek user di database
-	if err := a.DB.Where("username = ?", dto.Username).First(&foundUser).Error; err != nil {
+	// Cek user & verifikasi password
+	foundUser, err := VerifyCredentials(a.DB, dto.Username, dto.Password, ctx.IP())
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(throttled.RetryAfterSeconds()))
+			if throttled.Locked {
+				return utility.ErrorResponse(http.StatusTooManyRequests, "Account is temporarily locked due to too many failed login attempts", nil)
+			}
+			return utility.ErrorResponse(http.StatusTooManyRequests, "Too many failed login attempts, please try again later", nil)
+		}
+		if err == ErrEmailNotVerified {
+			return utility.ErrorResponse(http.StatusForbidden, "Email address has not been verified", nil)
+		}
 		return utility.ErrorResponse(http.StatusUnauthorized, "username or password wrong", nil)
 	}
 
-	// Verifikasi password
-	if !helper.CheckPasswordHash(dto.Password, *foundUser.Password) {
-		return utility.ErrorResponse(http.StatusUnauthorized, "username or password wrong", nil)
+	// Generate access token & refresh token (atau tantangan MFA jika aktif)
+	return CompleteLogin(a.DB, foundUser)
+}
+
+// Implementasi RotateRefreshToken (refresh token opaque dengan rotasi)
+func (a *AuthServiceImpl) RotateRefreshToken(ctx *fiber.Ctx) utility.APIResponse {
+	var dto RefreshTokenDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
 	}
 
-	// Generate JWT token
-	token, _ := helper.GenerateJWT(foundUser.Id, foundUser.Username, *foundUser.Fullname, string(foundUser.Role))
-	responseData := LoginResponse{
-		Id:       foundUser.Id,
-		Username: foundUser.Username,
-		Fullname: *foundUser.Fullname,
-		Role:     string(foundUser.Role),
-		Token:    token,
+	// Validasi DTO
+	if err := a.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	responseData, err := RotateRefreshToken(a.DB, dto.RefreshToken, "")
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenInvalid) || errors.Is(err, ErrRefreshTokenReused) {
+			return utility.ErrorResponse(http.StatusUnauthorized, err.Error(), nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to refresh token", []string{err.Error()})
 	}
-	return utility.SuccessResponse(http.StatusOK, "Login success", responseData)
+
+	return utility.SuccessResponse(http.StatusOK, "Token refreshed", responseData)
 }
 
-// Implementasi RefreshToken
-func (a *AuthServiceImpl) RefreshToken(ctx *fiber.Ctx) utility.APIResponse {
-	authHeader := ctx.Get("Authorization")
-	if authHeader == "" {
-		return utility.ErrorResponse(http.StatusUnauthorized, "Missing Authorization header", nil)
+// Implementasi Logout (mencabut access token saat ini dan refresh token terkait)
+func (a *AuthServiceImpl) Logout(ctx *fiber.Ctx) utility.APIResponse {
+	var dto LogoutDTO
+
+	// Body bersifat opsional
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&dto); err != nil {
+			return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+		}
 	}
 
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid token format", nil)
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	if dto.All {
+		if err := RevokeAllSessions(a.DB, userID); err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to logout", []string{err.Error()})
+		}
+		return utility.SuccessResponse(http.StatusOK, "All sessions logged out", nil)
 	}
 
-	tokenString := tokenParts[1]
-	token, err := helper.ValidateJWT(tokenString)
-	if err != nil || !token.Valid {
-		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid or expired token", nil)
+	// Cabut access token yang sedang dipakai sampai waktu kedaluwarsanya
+	jti, _ := ctx.Locals("jti").(string)
+	exp, _ := ctx.Locals("exp").(float64)
+	if jti != "" {
+		if err := helper.RevokeToken(jti, userID, time.Unix(int64(exp), 0)); err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to logout", []string{err.Error()})
+		}
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return utility.ErrorResponse(http.StatusInternalServerError, "Invalid token claims", nil)
+	if dto.RefreshToken != "" {
+		if err := RevokeRefreshToken(a.DB, userID, dto.RefreshToken); err != nil && !errors.Is(err, ErrRefreshTokenInvalid) {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to logout", []string{err.Error()})
+		}
 	}
 
-	// Generate token baru
-	newToken, _ := helper.GenerateJWT(int64(claims["user_id"].(float64)), claims["username"].(string), claims["fullname"].(string), claims["role"].(string))
+	return utility.SuccessResponse(http.StatusOK, "Logout success", nil)
+}
+
+// Implementasi LogoutUser (admin mencabut semua sesi milik user lain)
+func (a *AuthServiceImpl) LogoutUser(ctx *fiber.Ctx) utility.APIResponse {
+	id := ctx.Params("id")
+	var foundUser user.User
+
+	// Cek apakah user ada
+	if err := a.DB.Select("id").First(&foundUser, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	if err := RevokeAllSessions(a.DB, foundUser.Id); err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to logout user", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "All sessions of user logged out", nil)
+}
+
+// Implementasi ListKeys (metadata kunci penandatangan JWT)
+func (a *AuthServiceImpl) ListKeys(ctx *fiber.Ctx) utility.APIResponse {
+	keys, err := helper.ListSigningKeys()
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve signing keys", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", keys)
+}
+
+// Implementasi RotateKeys (membuat kunci aktif baru, kunci lama tetap dipakai verifikasi sampai pensiun)
+func (a *AuthServiceImpl) RotateKeys(ctx *fiber.Ctx) utility.APIResponse {
+	key, err := helper.RotateSigningKey()
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to rotate signing key", []string{err.Error()})
+	}
 
-	return utility.SuccessResponse(http.StatusOK, "Token refreshed", fiber.Map{
-		"access_token": newToken,
-	})
+	return utility.SuccessResponse(http.StatusCreated, "Signing key rotated", key)
 }
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// CredentialBackend sumber verifikasi username & password (password lokal, LDAP, dll.).
+// Authenticate mengembalikan ErrInvalidCredentials jika user tidak dikenal atau password salah,
+// error lain berarti backend sedang bermasalah.
+type CredentialBackend interface {
+	Name() string
+	Authenticate(db *gorm.DB, username string, password string) (user.User, error)
+}
+
+// credentialBackends backend yang dicoba berurutan saat login, diatur lewat AUTH_BACKENDS
+var credentialBackends = []CredentialBackend{LocalBackend{}}
+
+// SetupCredentialBackends membaca AUTH_BACKENDS (misal "local,ldap") dan menyiapkan backend yang dipilih
+func SetupCredentialBackends() error {
+	var backends []CredentialBackend
+	for _, name := range strings.Split(helper.GetEnv("AUTH_BACKENDS", user.AuthSourceLocal), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case user.AuthSourceLocal:
+			backends = append(backends, LocalBackend{})
+		case user.AuthSourceLDAP:
+			backend, err := NewLDAPBackend()
+			if err != nil {
+				return err
+			}
+			backends = append(backends, backend)
+		default:
+			return fmt.Errorf("unknown auth backend: %s", name)
+		}
+	}
+
+	if len(backends) == 0 {
+		return errors.New("AUTH_BACKENDS is empty")
+	}
+	credentialBackends = backends
+	return nil
+}
+
+// VerifyCredentials memeriksa username & password user aktif, dipakai oleh semua alur login dengan password.
+// Percobaan dibatasi per user dan per IP (backoff lalu lockout, lihat *LoginThrottledError).
+func VerifyCredentials(db *gorm.DB, username string, password string, ip string) (user.User, error) {
+	if username == "" || password == "" {
+		return user.User{}, ErrInvalidCredentials
+	}
+
+	if err := checkLoginAllowed(db, username, ip); err != nil {
+		return user.User{}, err
+	}
+
+	foundUser, err := authenticate(db, username, password)
+	if err == ErrInvalidCredentials {
+		recordLoginFailure(db, username, ip)
+	}
+	if err != nil {
+		return foundUser, err
+	}
+
+	recordLoginSuccess(db, foundUser.Id, ip)
+	return foundUser, nil
+}
+
+// authenticate mencoba backend berurutan; backend yang bermasalah dilewati agar backend lain tetap bisa dipakai
+func authenticate(db *gorm.DB, username string, password string) (user.User, error) {
+	for _, backend := range credentialBackends {
+		foundUser, err := backend.Authenticate(db, username, password)
+		if err != nil {
+			if err != ErrInvalidCredentials {
+				log.Printf("Backend %s gagal memverifikasi kredensial: %v", backend.Name(), err)
+			}
+			continue
+		}
+
+		if !foundUser.IsActive || foundUser.IsServiceAccount() {
+			return foundUser, ErrInvalidCredentials
+		}
+		if EmailVerificationRequired(foundUser) {
+			return foundUser, ErrEmailNotVerified
+		}
+		return foundUser, nil
+	}
+	return user.User{}, ErrInvalidCredentials
+}
+
+// LocalBackend memverifikasi password yang tersimpan (argon2id / bcrypt) di tabel users
+type LocalBackend struct{}
+
+// Name nama backend
+func (LocalBackend) Name() string {
+	return user.AuthSourceLocal
+}
+
+// Authenticate mencocokkan password dengan hash milik user lokal
+func (LocalBackend) Authenticate(db *gorm.DB, username string, password string) (user.User, error) {
+	var foundUser user.User
+	if err := db.Where("username = ? AND auth_source = ?", username, user.AuthSourceLocal).First(&foundUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return foundUser, ErrInvalidCredentials
+		}
+		return foundUser, err
+	}
+
+	if foundUser.Password == nil || !helper.CheckPasswordHash(password, *foundUser.Password) {
+		return foundUser, ErrInvalidCredentials
+	}
+
+	if helper.PasswordNeedsRehash(*foundUser.Password) {
+		rehashPassword(db, &foundUser, password)
+	}
+	return foundUser, nil
+}
+
+// rehashPassword meng-upgrade hash password ke algoritma / parameter terbaru setelah login berhasil.
+// Kegagalan hanya dicatat agar login tetap berjalan; update bersyarat agar tidak menimpa password yang baru diganti.
+func rehashPassword(db *gorm.DB, foundUser *user.User, password string) {
+	hashedPassword, err := helper.HashPassword(password)
+	if err != nil {
+		log.Println("Gagal meng-upgrade hash password:", err)
+		return
+	}
+
+	if err := db.Model(&user.User{}).
+		Where("id = ? AND password = ?", foundUser.Id, *foundUser.Password).
+		Update("password", hashedPassword).Error; err != nil {
+		log.Println("Gagal meng-upgrade hash password:", err)
+		return
+	}
+	foundUser.Password = &hashedPassword
+}
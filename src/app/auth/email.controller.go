@@ -0,0 +1,21 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type EmailController struct {
+	Service EmailService
+}
+
+func NewEmailController(service EmailService) *EmailController {
+	return &EmailController{Service: service}
+}
+
+func (ec *EmailController) Verify(ctx *fiber.Ctx) error {
+	response := ec.Service.Verify(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (ec *EmailController) ResendVerification(ctx *fiber.Ctx) error {
+	response := ec.Service.ResendVerification(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
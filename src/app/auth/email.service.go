@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// EmailService interface
+type EmailService interface {
+	Verify(ctx *fiber.Ctx) utility.APIResponse
+	ResendVerification(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// EmailServiceImpl struct
+type EmailServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Mailer   helper.Mailer
+}
+
+// Konstruktor untuk EmailService
+func NewEmailService(db *gorm.DB, mailer helper.Mailer) EmailService {
+	return &EmailServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+		Mailer:   mailer,
+	}
+}
+
+// emailVerificationTTL masa berlaku link verifikasi email (dalam jam), default 24 jam
+func emailVerificationTTL() time.Duration {
+	return time.Hour * time.Duration(helper.GetEnvInt("EMAIL_VERIFICATION_EXPIRATION", 24))
+}
+
+// EmailVerificationRequired true jika deployment mewajibkan email terverifikasi sebelum login
+// dan user belum memverifikasi emailnya
+func EmailVerificationRequired(u user.User) bool {
+	return helper.GetEnvBool("REQUIRE_EMAIL_VERIFICATION", false) && u.EmailVerifiedAt == nil
+}
+
+// Implementasi Verify (memakai token dari link verifikasi dan menandai email terverifikasi)
+func (e *EmailServiceImpl) Verify(ctx *fiber.Ctx) utility.APIResponse {
+	rawToken := ctx.Query("token")
+	if rawToken == "" {
+		return utility.ErrorResponse(http.StatusBadRequest, "Missing verification token", nil)
+	}
+
+	token, err := consumeOneTimeToken(e.DB, rawToken, PurposeEmailVerification)
+	if err != nil {
+		if err == ErrOneTimeTokenInvalid {
+			return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired verification token", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to verify email", []string{err.Error()})
+	}
+
+	if token.Payload == nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired verification token", nil)
+	}
+
+	// Token hanya berlaku untuk email yang dikirimi link; jika email sudah diganti, token tidak berlaku
+	result := e.DB.Model(&user.User{}).
+		Where("id = ? AND email = ?", token.UserId, *token.Payload).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to verify email", []string{result.Error.Error()})
+	}
+	if result.RowsAffected == 0 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired verification token", nil)
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Email verified successfully", nil)
+}
+
+// Implementasi ResendVerification (mengirim ulang link verifikasi ke email user yang sedang login)
+func (e *EmailServiceImpl) ResendVerification(ctx *fiber.Ctx) utility.APIResponse {
+	var foundUser user.User
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	if err := e.DB.First(&foundUser, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	if foundUser.Email == nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "No email address on this account", nil)
+	}
+	if foundUser.EmailVerifiedAt != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Email address is already verified", nil)
+	}
+
+	if err := sendVerificationEmail(e.DB, e.Mailer, foundUser); err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to send verification email", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Verification email sent", nil)
+}
+
+// sendVerificationEmail membuat token verifikasi yang terikat ke email user saat ini lalu mengirim link-nya
+func sendVerificationEmail(db *gorm.DB, mailer helper.Mailer, u user.User) error {
+	if u.Email == nil {
+		return nil
+	}
+
+	rawToken, err := createOneTimeToken(db, u.Id, PurposeEmailVerification, emailVerificationTTL(), *u.Email)
+	if err != nil {
+		return err
+	}
+
+	sendMailAsync(mailer, helper.Mail{
+		To:      *u.Email,
+		Subject: "Verify your email address",
+		Body: "Use the link below to verify your email address. The link expires in " +
+			emailVerificationTTL().String() + ".\n\n" +
+			helper.GetEnv("APP_URL", "http://localhost:3000") + "/auth/email/verify?token=" + rawToken +
+			"\n\nIf you did not create an account, you can ignore this email.",
+	})
+	return nil
+}
+
+// sendMailAsync mengirim email di background; kegagalan hanya dicatat di log
+func sendMailAsync(mailer helper.Mailer, mail helper.Mail) {
+	go func() {
+		if err := mailer.Send(mail); err != nil {
+			log.Printf("Gagal mengirim email %q: %v", mail.Subject, err)
+		}
+	}()
+}
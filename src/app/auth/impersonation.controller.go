@@ -0,0 +1,21 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type ImpersonationController struct {
+	Service ImpersonationService
+}
+
+func NewImpersonationController(service ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{Service: service}
+}
+
+func (ic *ImpersonationController) Start(ctx *fiber.Ctx) error {
+	response := ic.Service.Start(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (ic *ImpersonationController) Stop(ctx *fiber.Ctx) error {
+	response := ic.Service.Stop(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// ImpersonationService interface
+type ImpersonationService interface {
+	Start(ctx *fiber.Ctx) utility.APIResponse
+	Stop(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// ImpersonationServiceImpl struct
+type ImpersonationServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+// Konstruktor untuk ImpersonationService
+func NewImpersonationService(db *gorm.DB) ImpersonationService {
+	return &ImpersonationServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+	}
+}
+
+// Implementasi Start (admin mendapat token berumur pendek atas nama user lain, tanpa refresh token)
+func (i *ImpersonationServiceImpl) Start(ctx *fiber.Ctx) utility.APIResponse {
+	var dto ImpersonateDTO
+
+	// Impersonasi bertingkat tidak diizinkan, dan harus dimulai dari sesi login admin (bukan API key)
+	if impersonating, _ := ctx.Locals("impersonating").(bool); impersonating {
+		return utility.ErrorResponse(http.StatusForbidden, "Nested impersonation is not allowed", nil)
+	}
+	if ctx.Locals("token_type") == "api_key" {
+		return utility.ErrorResponse(http.StatusForbidden, "Impersonation requires an interactive session", nil)
+	}
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := i.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	actorID := int64(ctx.Locals("user_id").(float64))
+	actorUsername, _ := ctx.Locals("username").(string)
+
+	var target user.User
+	if err := i.DB.First(&target, ctx.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	// Admin lain tidak bisa di-impersonasi agar tidak ada eskalasi lewat akun admin lain
+	if target.Id == actorID {
+		return utility.ErrorResponse(http.StatusBadRequest, "You cannot impersonate yourself", nil)
+	}
+	if target.Role == user.ADMIN {
+		return utility.ErrorResponse(http.StatusForbidden, "Admins cannot be impersonated", nil)
+	}
+	if !target.IsActive {
+		return utility.ErrorResponse(http.StatusBadRequest, "User is not active", nil)
+	}
+
+	fullname := ""
+	if target.Fullname != nil {
+		fullname = *target.Fullname
+	}
+	token, err := helper.GenerateImpersonationJWT(target.Id, target.Username, fullname, string(target.Role), actorID, actorUsername, helper.ImpersonationTTL())
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to start impersonation", []string{err.Error()})
+	}
+
+	RecordAudit(i.DB, ctx, AuditImpersonationStart, &actorID, &target.Id, map[string]interface{}{
+		"reason": dto.Reason,
+	})
+
+	return utility.SuccessResponse(http.StatusOK, "Impersonation started", ImpersonationResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresIn: int(helper.ImpersonationTTL().Seconds()),
+		UserId:    target.Id,
+		Username:  target.Username,
+		ActorId:   actorID,
+	})
+}
+
+// Implementasi Stop (mencabut token impersonasi yang sedang dipakai)
+func (i *ImpersonationServiceImpl) Stop(ctx *fiber.Ctx) utility.APIResponse {
+	if impersonating, _ := ctx.Locals("impersonating").(bool); !impersonating {
+		return utility.ErrorResponse(http.StatusBadRequest, "You are not impersonating a user", nil)
+	}
+
+	userID := int64(ctx.Locals("user_id").(float64))
+	actorID := int64(ctx.Locals("actor_id").(float64))
+	jti, _ := ctx.Locals("jti").(string)
+	exp, _ := ctx.Locals("exp").(float64)
+
+	if err := helper.RevokeToken(jti, userID, time.Unix(int64(exp), 0)); err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to stop impersonation", []string{err.Error()})
+	}
+
+	RecordAudit(i.DB, ctx, AuditImpersonationStop, &actorID, &userID, nil)
+
+	return utility.SuccessResponse(http.StatusOK, "Impersonation stopped", nil)
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// LDAPBackend memverifikasi password ke LDAP / Active Directory dengan pola search-then-bind,
+// lalu menyinkronkan profil dan grup user ke tabel users
+type LDAPBackend struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // Akun layanan untuk mencari user, kosong berarti anonymous
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // %s diganti username yang sudah di-escape
+	FullnameAttr       string
+	EmailAttr          string
+	GroupAttr          string
+	RoleMap            map[string]user.Role // DN grup (huruf kecil) -> role lokal
+	DefaultRole        user.Role
+	AutoProvision      bool
+	Timeout            time.Duration
+}
+
+// NewLDAPBackend membaca konfigurasi LDAP dari env LDAP_*
+func NewLDAPBackend() (*LDAPBackend, error) {
+	backend := &LDAPBackend{
+		URL:                os.Getenv("LDAP_URL"),
+		StartTLS:           helper.GetEnvBool("LDAP_START_TLS", false),
+		InsecureSkipVerify: helper.GetEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+		BindDN:             os.Getenv("LDAP_BIND_DN"),
+		BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:             os.Getenv("LDAP_BASE_DN"),
+		UserFilter:         helper.GetEnv("LDAP_USER_FILTER", "(&(objectClass=user)(sAMAccountName=%s))"),
+		FullnameAttr:       helper.GetEnv("LDAP_FULLNAME_ATTRIBUTE", "displayName"),
+		EmailAttr:          helper.GetEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		GroupAttr:          helper.GetEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		RoleMap:            map[string]user.Role{},
+		DefaultRole:        user.Role(helper.GetEnv("LDAP_DEFAULT_ROLE", string(user.USER))),
+		AutoProvision:      helper.GetEnvBool("LDAP_AUTO_PROVISION", true),
+		Timeout:            time.Second * time.Duration(helper.GetEnvInt("LDAP_TIMEOUT", 5)),
+	}
+
+	if backend.URL == "" || backend.BaseDN == "" {
+		return nil, errors.New("LDAP_URL and LDAP_BASE_DN are required")
+	}
+	if !strings.Contains(backend.UserFilter, "%s") {
+		return nil, errors.New("LDAP_USER_FILTER must contain %s")
+	}
+	if backend.DefaultRole != user.ADMIN && backend.DefaultRole != user.USER {
+		return nil, fmt.Errorf("invalid LDAP_DEFAULT_ROLE: %s", backend.DefaultRole)
+	}
+
+	// Format: "CN=App Admins,OU=Groups,DC=corp,DC=local=admin;CN=Staff,OU=Groups,DC=corp,DC=local=user".
+	// Dipisah titik koma karena DN sendiri mengandung koma; role diambil dari "=" terakhir.
+	for _, pair := range strings.Split(os.Getenv("LDAP_ROLE_MAP"), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		index := strings.LastIndex(pair, "=")
+		if index <= 0 {
+			return nil, fmt.Errorf("invalid LDAP_ROLE_MAP entry: %s", pair)
+		}
+		role := user.Role(strings.TrimSpace(pair[index+1:]))
+		if role != user.ADMIN && role != user.USER {
+			return nil, fmt.Errorf("invalid LDAP_ROLE_MAP entry: %s", pair)
+		}
+		backend.RoleMap[normalizeDN(pair[:index])] = role
+	}
+	return backend, nil
+}
+
+// Name nama backend
+func (l *LDAPBackend) Name() string {
+	return user.AuthSourceLDAP
+}
+
+// Authenticate mencari entry user dengan akun layanan, lalu bind sebagai user tersebut
+func (l *LDAPBackend) Authenticate(db *gorm.DB, username string, password string) (user.User, error) {
+	// User lokal dengan username yang sama tidak boleh diambil alih oleh entry direktori
+	var foundUser user.User
+	err := db.Where("username = ?", username).First(&foundUser).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return foundUser, err
+	}
+	exists := err == nil
+	if exists && foundUser.AuthSource != user.AuthSourceLDAP {
+		return foundUser, ErrInvalidCredentials
+	}
+	if !exists && !l.AutoProvision {
+		return foundUser, ErrInvalidCredentials
+	}
+
+	// Bind dengan password kosong adalah "unauthenticated bind" yang selalu berhasil
+	if password == "" {
+		return foundUser, ErrInvalidCredentials
+	}
+
+	entry, err := l.bindUser(username, password)
+	if err != nil {
+		return foundUser, err
+	}
+
+	return l.syncUser(db, foundUser, exists, username, entry)
+}
+
+// bindUser melakukan search-then-bind dan mengembalikan entry user di direktori
+func (l *LDAPBackend) bindUser(username string, password string) (*ldap.Entry, error) {
+	conn, err := ldap.DialURL(l.URL, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: l.InsecureSkipVerify}))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetTimeout(l.Timeout)
+
+	if l.StartTLS {
+		if err := conn.StartTLS(&tls.Config{InsecureSkipVerify: l.InsecureSkipVerify}); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.BindDN != "" {
+		if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+			return nil, fmt.Errorf("service account bind failed: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(l.Timeout.Seconds()), false,
+		fmt.Sprintf(l.UserFilter, ldap.EscapeFilter(username)),
+		[]string{l.FullnameAttr, l.EmailAttr, l.GroupAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	// Username harus menunjuk tepat satu entry
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// syncUser membuat atau memperbarui user lokal dari atribut direktori
+func (l *LDAPBackend) syncUser(db *gorm.DB, foundUser user.User, exists bool, username string, entry *ldap.Entry) (user.User, error) {
+	groups := entry.GetAttributeValues(l.GroupAttr)
+	if groups == nil {
+		groups = []string{}
+	}
+
+	foundUser.Username = username
+	foundUser.AuthSource = user.AuthSourceLDAP
+	foundUser.Groups = groups
+	if fullname := entry.GetAttributeValue(l.FullnameAttr); fullname != "" {
+		foundUser.Fullname = &fullname
+	}
+	if !exists {
+		foundUser.IsActive = true
+		foundUser.Role = l.DefaultRole
+	}
+	if role, ok := l.mapRole(groups); ok {
+		foundUser.Role = role
+	} else if len(l.RoleMap) > 0 {
+		foundUser.Role = l.DefaultRole
+	}
+
+	// Email dari direktori dianggap terverifikasi; tidak dipakai jika sudah milik user lain
+	email := helper.NormalizeEmail(entry.GetAttributeValue(l.EmailAttr))
+	if email != "" && (foundUser.Email == nil || *foundUser.Email != email) {
+		taken, err := user.EmailTaken(db, email, foundUser.Id)
+		if err != nil {
+			return foundUser, err
+		}
+		if !taken {
+			now := time.Now()
+			foundUser.Email = &email
+			foundUser.EmailVerifiedAt = &now
+		}
+	}
+
+	if err := db.Save(&foundUser).Error; err != nil {
+		return foundUser, err
+	}
+	return foundUser, nil
+}
+
+// mapRole memilih role tertinggi dari grup yang terdaftar di LDAP_ROLE_MAP
+func (l *LDAPBackend) mapRole(groups []string) (user.Role, bool) {
+	matched := false
+	role := user.USER
+	for _, group := range groups {
+		mapped, ok := l.RoleMap[normalizeDN(group)]
+		if !ok {
+			continue
+		}
+		matched = true
+		if mapped == user.ADMIN {
+			return user.ADMIN, true
+		}
+	}
+	return role, matched
+}
+
+// normalizeDN menyeragamkan DN agar perbandingan tidak peka huruf besar / spasi setelah koma
+func normalizeDN(dn string) string {
+	parts := strings.Split(strings.TrimSpace(dn), ",")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, ",")
+}
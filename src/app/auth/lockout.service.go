@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// LoginThrottledError login ditolak sementara karena terlalu banyak percobaan gagal
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // true jika akun / IP terkunci, false jika hanya backoff
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("account is temporarily locked, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter)
+}
+
+// RetryAfterSeconds nilai header Retry-After (dibulatkan ke atas, minimal 1 detik)
+func (e *LoginThrottledError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// lockoutPolicy ambang dan durasi backoff / lockout dari env
+type lockoutPolicy struct {
+	BackoffAfter int           // Jumlah gagal sebelum jeda mulai berlaku
+	BackoffBase  time.Duration // Jeda pertama, berlipat dua untuk setiap kegagalan berikutnya
+	BackoffMax   time.Duration
+	UserLimit    int // Jumlah gagal sebelum akun dikunci
+	IPLimit      int // Jumlah gagal sebelum IP dikunci
+	LockDuration time.Duration
+	ResetAfter   time.Duration // Penghitung dianggap nol jika tidak ada kegagalan selama selang ini
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		BackoffAfter: helper.GetEnvInt("LOGIN_BACKOFF_AFTER", 3),
+		BackoffBase:  time.Second,
+		BackoffMax:   time.Second * time.Duration(helper.GetEnvInt("LOGIN_BACKOFF_MAX", 30)),
+		UserLimit:    helper.GetEnvInt("LOCKOUT_THRESHOLD", 10),
+		IPLimit:      helper.GetEnvInt("LOCKOUT_IP_THRESHOLD", 50),
+		LockDuration: time.Minute * time.Duration(helper.GetEnvInt("LOCKOUT_DURATION", 15)),
+		ResetAfter:   time.Minute * time.Duration(helper.GetEnvInt("LOCKOUT_RESET_AFTER", 60)),
+	}
+}
+
+// backoff jeda minimal setelah kegagalan ke-n: base * 2^(n - BackoffAfter), dibatasi BackoffMax
+func (p lockoutPolicy) backoff(failures int) time.Duration {
+	if failures < p.BackoffAfter {
+		return 0
+	}
+	exponent := failures - p.BackoffAfter
+	if exponent > 16 {
+		return p.BackoffMax
+	}
+	return time.Duration(math.Min(float64(p.BackoffBase)*math.Pow(2, float64(exponent)), float64(p.BackoffMax)))
+}
+
+// effectiveFailures penghitung gagal setelah memperhitungkan ResetAfter
+func (p lockoutPolicy) effectiveFailures(failures int, lastFailedAt *time.Time, now time.Time) int {
+	if lastFailedAt == nil || now.Sub(*lastFailedAt) > p.ResetAfter {
+		return 0
+	}
+	return failures
+}
+
+// throttle menghitung sisa waktu tunggu dari penghitung gagal, nil jika login boleh dicoba
+func (p lockoutPolicy) throttle(failures int, lastFailedAt *time.Time, lockedUntil *time.Time, now time.Time) *LoginThrottledError {
+	if lockedUntil != nil && now.Before(*lockedUntil) {
+		return &LoginThrottledError{RetryAfter: lockedUntil.Sub(now), Locked: true}
+	}
+
+	failures = p.effectiveFailures(failures, lastFailedAt, now)
+	if wait := p.backoff(failures); wait > 0 && now.Before(lastFailedAt.Add(wait)) {
+		return &LoginThrottledError{RetryAfter: lastFailedAt.Add(wait).Sub(now)}
+	}
+	return nil
+}
+
+// checkLoginAllowed menolak percobaan login selama akun atau IP masih dalam masa backoff / lockout
+func checkLoginAllowed(db *gorm.DB, username string, ip string) error {
+	policy := loadLockoutPolicy()
+	now := time.Now()
+
+	var failure LoginFailure
+	if ip != "" && db.Where("ip = ?", ip).First(&failure).Error == nil {
+		if err := policy.throttle(failure.FailedLogins, failure.LastFailedAt, failure.LockedUntil, now); err != nil {
+			return err
+		}
+	}
+
+	var foundUser user.User
+	if db.Select("id", "failed_logins", "last_failed_at", "locked_until").Where("username = ?", username).First(&foundUser).Error == nil {
+		if err := policy.throttle(foundUser.FailedLogins, foundUser.LastFailedAt, foundUser.LockedUntil, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure menaikkan penghitung gagal untuk user (jika ada) dan IP, lalu mengunci jika melewati ambang.
+// Penghitung dinaikkan di database (failed_logins + 1) lalu dibaca ulang, sehingga percobaan paralel tidak saling menimpa.
+func recordLoginFailure(db *gorm.DB, username string, ip string) {
+	policy := loadLockoutPolicy()
+	now := time.Now()
+	staleBefore := now.Add(-policy.ResetAfter)
+
+	var foundUser user.User
+	if db.Select("id").Where("username = ?", username).First(&foundUser).Error == nil {
+		failures, err := incrementFailures(db.Model(&user.User{}).Where("id = ?", foundUser.Id), staleBefore, now)
+		if err != nil {
+			log.Println("Gagal menyimpan login gagal:", err)
+		} else if failures >= policy.UserLimit {
+			lockedUntil := now.Add(policy.LockDuration)
+			if err := db.Model(&user.User{}).Where("id = ?", foundUser.Id).Update("locked_until", lockedUntil).Error; err != nil {
+				log.Println("Gagal mengunci akun:", err)
+			}
+			log.Printf("Akun %q dikunci sampai %s setelah %d login gagal", username, lockedUntil.Format(time.RFC3339), failures)
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	// Pastikan baris IP ada tanpa menimpa penghitung milik request lain
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&LoginFailure{Ip: ip}).Error; err != nil {
+		log.Println("Gagal menyimpan login gagal per IP:", err)
+		return
+	}
+	failures, err := incrementFailures(db.Model(&LoginFailure{}).Where("ip = ?", ip), staleBefore, now)
+	if err != nil {
+		log.Println("Gagal menyimpan login gagal per IP:", err)
+		return
+	}
+	if failures >= policy.IPLimit {
+		if err := db.Model(&LoginFailure{}).Where("ip = ?", ip).Update("locked_until", now.Add(policy.LockDuration)).Error; err != nil {
+			log.Println("Gagal mengunci IP:", err)
+		}
+	}
+}
+
+// incrementFailures menaikkan failed_logins secara atomik pada baris yang dipilih scope lalu mengembalikan nilai barunya.
+// Penghitung yang kegagalan terakhirnya sebelum staleBefore dinolkan dulu (ResetAfter).
+func incrementFailures(scope *gorm.DB, staleBefore time.Time, now time.Time) (int, error) {
+	if err := scope.Session(&gorm.Session{}).
+		Where("last_failed_at IS NULL OR last_failed_at < ?", staleBefore).
+		UpdateColumn("failed_logins", 0).Error; err != nil {
+		return 0, err
+	}
+	if err := scope.Session(&gorm.Session{}).UpdateColumns(map[string]interface{}{
+		"failed_logins":  gorm.Expr("failed_logins + 1"),
+		"last_failed_at": now,
+	}).Error; err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err := scope.Session(&gorm.Session{}).Select("failed_logins").Scan(&failures).Error
+	return failures, err
+}
+
+// recordLoginSuccess mereset penghitung gagal milik user dan milik IP asal login
+func recordLoginSuccess(db *gorm.DB, userID int64, ip string) {
+	if err := db.Model(&user.User{}).
+		Where("id = ? AND (failed_logins > 0 OR locked_until IS NOT NULL)", userID).
+		Updates(map[string]interface{}{"failed_logins": 0, "last_failed_at": nil, "locked_until": nil}).Error; err != nil {
+		log.Println("Gagal mereset login gagal:", err)
+	}
+
+	if ip == "" {
+		return
+	}
+	if err := db.Model(&LoginFailure{}).
+		Where("ip = ? AND (failed_logins > 0 OR locked_until IS NOT NULL)", ip).
+		Updates(map[string]interface{}{"failed_logins": 0, "last_failed_at": nil, "locked_until": nil}).Error; err != nil {
+		log.Println("Gagal mereset login gagal per IP:", err)
+	}
+}
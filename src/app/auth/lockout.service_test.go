@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/achyar10/go-auth/src/app/user"
+)
+
+func TestRecordLoginFailureConcurrent(t *testing.T) {
+	t.Setenv("LOCKOUT_THRESHOLD", "1000")
+	t.Setenv("LOCKOUT_IP_THRESHOLD", "1000")
+	db := newTestDB(t)
+	if err := db.Create(&user.User{Username: "budi", Role: user.USER}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	const attempts = 25
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordLoginFailure(db, "budi", "10.0.0.1")
+		}()
+	}
+	wg.Wait()
+
+	var foundUser user.User
+	db.Where("username = ?", "budi").First(&foundUser)
+	if foundUser.FailedLogins != attempts {
+		t.Errorf("user failed_logins = %d, want %d", foundUser.FailedLogins, attempts)
+	}
+	var failure LoginFailure
+	db.Where("ip = ?", "10.0.0.1").First(&failure)
+	if failure.FailedLogins != attempts {
+		t.Errorf("ip failed_logins = %d, want %d", failure.FailedLogins, attempts)
+	}
+}
+
+func TestRecordLoginFailureLocksAndResets(t *testing.T) {
+	t.Setenv("LOCKOUT_THRESHOLD", "3")
+	t.Setenv("LOCKOUT_IP_THRESHOLD", "5")
+	t.Setenv("LOCKOUT_RESET_AFTER", "60")
+	db := newTestDB(t)
+	if err := db.Create(&user.User{Username: "budi", Role: user.USER}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		recordLoginFailure(db, "budi", "10.0.0.2")
+	}
+	var foundUser user.User
+	db.Where("username = ?", "budi").First(&foundUser)
+	if foundUser.FailedLogins != 3 || foundUser.LockedUntil == nil {
+		t.Fatalf("after 3 failures: failed_logins=%d locked_until=%v, want 3 and locked", foundUser.FailedLogins, foundUser.LockedUntil)
+	}
+	var failure LoginFailure
+	db.Where("ip = ?", "10.0.0.2").First(&failure)
+	if failure.FailedLogins != 3 || failure.LockedUntil != nil {
+		t.Fatalf("ip after 3 failures: failed_logins=%d locked_until=%v, want 3 and not locked", failure.FailedLogins, failure.LockedUntil)
+	}
+
+	// Kegagalan terakhir lebih lama dari LOCKOUT_RESET_AFTER: penghitung mulai lagi dari 1
+	stale := time.Now().Add(-2 * time.Hour)
+	db.Model(&user.User{}).Where("id = ?", foundUser.Id).Updates(map[string]interface{}{"last_failed_at": stale, "locked_until": nil})
+	db.Model(&LoginFailure{}).Where("ip = ?", "10.0.0.2").Update("last_failed_at", stale)
+
+	recordLoginFailure(db, "budi", "10.0.0.2")
+	var resetUser user.User
+	db.Where("username = ?", "budi").First(&resetUser)
+	if resetUser.FailedLogins != 1 || resetUser.LockedUntil != nil {
+		t.Errorf("after reset: failed_logins=%d locked_until=%v, want 1 and unlocked", resetUser.FailedLogins, resetUser.LockedUntil)
+	}
+	var resetFailure LoginFailure
+	db.Where("ip = ?", "10.0.0.2").First(&resetFailure)
+	if resetFailure.FailedLogins != 1 {
+		t.Errorf("ip after reset: failed_logins=%d, want 1", resetFailure.FailedLogins)
+	}
+}
+
+func TestRecordLoginFailureUnknownUser(t *testing.T) {
+	db := newTestDB(t)
+
+	recordLoginFailure(db, "tidak-ada", "10.0.0.3")
+
+	var failure LoginFailure
+	if err := db.Where("ip = ?", "10.0.0.3").First(&failure).Error; err != nil || failure.FailedLogins != 1 {
+		t.Errorf("ip failed_logins=%d err=%v, want 1", failure.FailedLogins, err)
+	}
+}
+
+func TestRecordLoginSuccessResetsUserAndIP(t *testing.T) {
+	db := newTestDB(t)
+	account := user.User{Username: "budi", Role: user.USER}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		recordLoginFailure(db, "budi", "10.0.0.4")
+	}
+	recordLoginFailure(db, "siti", "10.0.0.5")
+
+	recordLoginSuccess(db, account.Id, "10.0.0.4")
+
+	var foundUser user.User
+	db.First(&foundUser, account.Id)
+	if foundUser.FailedLogins != 0 || foundUser.LastFailedAt != nil {
+		t.Errorf("user failed_logins=%d last_failed_at=%v, want reset", foundUser.FailedLogins, foundUser.LastFailedAt)
+	}
+	var failure LoginFailure
+	db.Where("ip = ?", "10.0.0.4").First(&failure)
+	if failure.FailedLogins != 0 || failure.LastFailedAt != nil {
+		t.Errorf("ip failed_logins=%d last_failed_at=%v, want reset", failure.FailedLogins, failure.LastFailedAt)
+	}
+
+	// IP lain tidak ikut direset
+	var other LoginFailure
+	db.Where("ip = ?", "10.0.0.5").First(&other)
+	if other.FailedLogins != 1 {
+		t.Errorf("other ip failed_logins=%d, want 1", other.FailedLogins)
+	}
+}
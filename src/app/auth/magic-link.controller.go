@@ -0,0 +1,21 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type MagicLinkController struct {
+	Service MagicLinkService
+}
+
+func NewMagicLinkController(service MagicLinkService) *MagicLinkController {
+	return &MagicLinkController{Service: service}
+}
+
+func (mc *MagicLinkController) Send(ctx *fiber.Ctx) error {
+	response := mc.Service.Send(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (mc *MagicLinkController) Verify(ctx *fiber.Ctx) error {
+	response := mc.Service.Verify(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
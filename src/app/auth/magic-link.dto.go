@@ -0,0 +1,5 @@
+package auth
+
+type MagicLinkDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
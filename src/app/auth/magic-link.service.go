@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// magicLinkThrottleWindow jendela waktu untuk membatasi jumlah magic link per user
+const magicLinkThrottleWindow = 15 * time.Minute
+
+// MagicLinkService interface
+type MagicLinkService interface {
+	Send(ctx *fiber.Ctx) utility.APIResponse
+	Verify(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// MagicLinkServiceImpl struct
+type MagicLinkServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Mailer   helper.Mailer
+}
+
+// Konstruktor untuk MagicLinkService
+func NewMagicLinkService(db *gorm.DB, mailer helper.Mailer) MagicLinkService {
+	return &MagicLinkServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+		Mailer:   mailer,
+	}
+}
+
+// magicLinkTTL masa berlaku magic link (dalam menit), default 10 menit
+func magicLinkTTL() time.Duration {
+	return time.Minute * time.Duration(helper.GetEnvInt("MAGIC_LINK_EXPIRATION", 10))
+}
+
+// Implementasi Send (mengirim link login sekali pakai ke email user).
+// Response selalu sama agar tidak membocorkan apakah email terdaftar atau sedang dibatasi.
+func (m *MagicLinkServiceImpl) Send(ctx *fiber.Ctx) utility.APIResponse {
+	var dto MagicLinkDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := m.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	response := utility.SuccessResponse(http.StatusOK, "If the account exists, a login link has been sent", nil)
+
+	var foundUser user.User
+	// Link login hanya dikirim ke email yang sudah diverifikasi
+	if err := m.DB.Where("email = ? AND email_verified_at IS NOT NULL AND is_active = ? AND kind = ?", helper.NormalizeEmail(dto.Email), true, user.KindHuman).First(&foundUser).Error; err != nil {
+		return response
+	}
+
+	// Batasi jumlah link per user dalam satu jendela waktu
+	var recent int64
+	if err := m.DB.Model(&OneTimeToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", foundUser.Id, PurposeMagicLink, time.Now().Add(-magicLinkThrottleWindow)).
+		Count(&recent).Error; err != nil {
+		log.Println("Gagal mengecek throttle magic link:", err)
+		return response
+	}
+	if recent >= int64(helper.GetEnvInt("MAGIC_LINK_MAX_PER_WINDOW", 3)) {
+		log.Printf("Magic link untuk user %d dibatasi (%d link dalam %s)", foundUser.Id, recent, magicLinkThrottleWindow)
+		return response
+	}
+
+	// Token terikat ke email saat ini; link lama otomatis tidak berlaku
+	rawToken, err := createOneTimeToken(m.DB, foundUser.Id, PurposeMagicLink, magicLinkTTL(), *foundUser.Email)
+	if err != nil {
+		log.Println("Gagal membuat magic link:", err)
+		return response
+	}
+
+	sendMailAsync(m.Mailer, helper.Mail{
+		To:      *foundUser.Email,
+		Subject: "Your login link",
+		Body: "Use the link below to log in. The link expires in " +
+			magicLinkTTL().String() + " and can only be used once.\n\n" +
+			helper.GetEnv("APP_URL", "http://localhost:3000") + "/auth/magic-link/verify?token=" + rawToken +
+			"\n\nIf you did not request this link, you can ignore this email.",
+	})
+
+	return response
+}
+
+// Implementasi Verify (menukar magic link dengan sesi, sama seperti Login)
+func (m *MagicLinkServiceImpl) Verify(ctx *fiber.Ctx) utility.APIResponse {
+	rawToken := ctx.Query("token")
+	if rawToken == "" {
+		return utility.ErrorResponse(http.StatusBadRequest, "Missing login token", nil)
+	}
+
+	token, err := consumeOneTimeToken(m.DB, rawToken, PurposeMagicLink)
+	if err != nil {
+		if err == ErrOneTimeTokenInvalid {
+			return utility.ErrorResponse(http.StatusUnauthorized, "Invalid or expired login link", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to verify login link", []string{err.Error()})
+	}
+
+	// Email harus masih sama (dan masih terverifikasi) dengan email yang dikirimi link
+	var foundUser user.User
+	if token.Payload == nil ||
+		m.DB.Where("id = ? AND email = ? AND email_verified_at IS NOT NULL AND is_active = ?", token.UserId, *token.Payload, true).First(&foundUser).Error != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid or expired login link", nil)
+	}
+
+	// Generate access token & refresh token (atau tantangan MFA jika aktif)
+	return CompleteLogin(m.DB, foundUser)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type MFAController struct {
+	Service MFAService
+}
+
+func NewMFAController(service MFAService) *MFAController {
+	return &MFAController{Service: service}
+}
+
+func (mc *MFAController) EnrollTOTP(ctx *fiber.Ctx) error {
+	response := mc.Service.EnrollTOTP(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (mc *MFAController) ConfirmTOTP(ctx *fiber.Ctx) error {
+	response := mc.Service.ConfirmTOTP(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (mc *MFAController) DisableTOTP(ctx *fiber.Ctx) error {
+	response := mc.Service.DisableTOTP(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (mc *MFAController) Verify(ctx *fiber.Ctx) error {
+	response := mc.Service.Verify(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (mc *MFAController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	response := mc.Service.RegenerateRecoveryCodes(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -0,0 +1,10 @@
+package auth
+
+type MFACodeDTO struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAVerifyDTO struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
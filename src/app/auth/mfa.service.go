@@ -0,0 +1,419 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+const (
+	// mfaChallengeTTL masa berlaku token tantangan MFA
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount jumlah kode pemulihan yang dibuat setiap kali generate
+	recoveryCodeCount = 10
+)
+
+// MFAService interface
+type MFAService interface {
+	EnrollTOTP(ctx *fiber.Ctx) utility.APIResponse
+	ConfirmTOTP(ctx *fiber.Ctx) utility.APIResponse
+	DisableTOTP(ctx *fiber.Ctx) utility.APIResponse
+	Verify(ctx *fiber.Ctx) utility.APIResponse
+	RegenerateRecoveryCodes(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// MFAServiceImpl struct
+type MFAServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+// Konstruktor untuk MFAService
+func NewMFAService(db *gorm.DB) MFAService {
+	return &MFAServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+	}
+}
+
+// CompleteLogin dipanggil setelah faktor pertama berhasil: jika MFA aktif (TOTP atau passkey)
+// kembalikan token tantangan, jika tidak langsung terbitkan sesi
+func CompleteLogin(db *gorm.DB, u user.User) utility.APIResponse {
+	// Service account hanya bisa memakai API key atau client credentials
+	if u.IsServiceAccount() {
+		return utility.ErrorResponse(http.StatusForbidden, "Service accounts cannot sign in interactively", nil)
+	}
+
+	methods, err := MFAMethods(db, u)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create session", []string{err.Error()})
+	}
+
+	if len(methods) > 0 {
+		mfaToken, err := helper.GeneratePurposeToken("mfa", u.Id, mfaChallengeTTL)
+		if err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create MFA challenge", []string{err.Error()})
+		}
+		return utility.SuccessResponse(http.StatusOK, "MFA required", MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			Methods:     methods,
+			ExpiresIn:   int(mfaChallengeTTL.Seconds()),
+		})
+	}
+
+	responseData, err := IssueSession(db, u)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create session", []string{err.Error()})
+	}
+	return utility.SuccessResponse(http.StatusOK, "Login success", responseData)
+}
+
+// ParseMFAChallenge memvalidasi token tantangan MFA dan mengembalikan user pemiliknya
+func ParseMFAChallenge(db *gorm.DB, mfaToken string) (user.User, jwt.MapClaims, error) {
+	var foundUser user.User
+
+	claims, err := helper.ValidatePurposeToken(mfaToken, "mfa")
+	if err != nil {
+		return foundUser, nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(float64)
+	issuedAt, ok := helper.TokenIssuedAt(claims)
+	if !ok || helper.IsTokenRevoked(jti, int64(userID), issuedAt) {
+		return foundUser, nil, errors.New("MFA token has been used")
+	}
+
+	if err := db.First(&foundUser, int64(userID)).Error; err != nil {
+		return foundUser, nil, err
+	}
+	return foundUser, claims, nil
+}
+
+// RecordMFAFailure mencatat kode salah untuk token tantangan MFA. Setelah MFA_MAX_ATTEMPTS kali gagal
+// token dicabut sehingga user harus mengulang login dari faktor pertama.
+func RecordMFAFailure(claims jwt.MapClaims, userID int64) {
+	jti, _ := claims["jti"].(string)
+	result, err := helper.GetRateLimitStore().Hit("mfa_challenge:"+jti, helper.GetEnvInt("MFA_MAX_ATTEMPTS", 5), mfaChallengeTTL)
+	if err != nil {
+		log.Println("Gagal mencatat percobaan MFA:", err)
+		return
+	}
+	if !result.Allowed || result.Remaining <= 0 {
+		ConsumeMFAChallenge(claims, userID)
+	}
+}
+
+// ConsumeMFAChallenge mencabut token tantangan MFA agar hanya bisa dipakai sekali
+func ConsumeMFAChallenge(claims jwt.MapClaims, userID int64) {
+	jti, _ := claims["jti"].(string)
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		_ = helper.RevokeToken(jti, userID, expiresAt.Time)
+	}
+}
+
+// Implementasi EnrollTOTP (membuat secret baru, MFA belum aktif sampai dikonfirmasi)
+func (m *MFAServiceImpl) EnrollTOTP(ctx *fiber.Ctx) utility.APIResponse {
+	foundUser, response := m.currentUser(ctx)
+	if foundUser == nil {
+		return response
+	}
+
+	if foundUser.MfaEnabled {
+		return utility.ErrorResponse(http.StatusConflict, "MFA is already enabled", nil)
+	}
+
+	enrollment, err := helper.GenerateTOTP(foundUser.Username)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to generate TOTP secret", []string{err.Error()})
+	}
+
+	encryptedSecret, err := helper.Encrypt(enrollment.Secret)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to store TOTP secret", []string{err.Error()})
+	}
+
+	// Secret baru memulai hitungan time step dari awal
+	if err := m.DB.Model(foundUser).Updates(map[string]interface{}{
+		"totp_secret":    encryptedSecret,
+		"totp_last_step": nil,
+	}).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to store TOTP secret", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Scan the QR code and confirm with a code", enrollment)
+}
+
+// Implementasi ConfirmTOTP (mengaktifkan MFA dengan kode pertama dari authenticator)
+func (m *MFAServiceImpl) ConfirmTOTP(ctx *fiber.Ctx) utility.APIResponse {
+	var dto MFACodeDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := m.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	foundUser, response := m.currentUser(ctx)
+	if foundUser == nil {
+		return response
+	}
+
+	if foundUser.MfaEnabled {
+		return utility.ErrorResponse(http.StatusConflict, "MFA is already enabled", nil)
+	}
+	if foundUser.TotpSecret == nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "TOTP enrollment has not been started", nil)
+	}
+
+	if !checkTOTP(m.DB, *foundUser, dto.Code) {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid code", nil)
+	}
+
+	// Aktifkan MFA sekaligus buat kode pemulihan
+	var recoveryCodes []string
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(foundUser).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err := replaceRecoveryCodes(tx, foundUser.Id)
+		recoveryCodes = codes
+		return err
+	})
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to enable MFA", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "MFA enabled, store the recovery codes in a safe place", fiber.Map{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Implementasi DisableTOTP (menonaktifkan MFA, wajib menyertakan kode yang valid)
+func (m *MFAServiceImpl) DisableTOTP(ctx *fiber.Ctx) utility.APIResponse {
+	var dto MFACodeDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := m.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	foundUser, response := m.currentUser(ctx)
+	if foundUser == nil {
+		return response
+	}
+
+	if !foundUser.MfaEnabled {
+		return utility.ErrorResponse(http.StatusBadRequest, "MFA is not enabled", nil)
+	}
+	if !checkTOTP(m.DB, *foundUser, dto.Code) {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid code", nil)
+	}
+
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(foundUser).Updates(map[string]interface{}{
+			"mfa_enabled":    false,
+			"totp_secret":    nil,
+			"totp_last_step": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", foundUser.Id).Delete(&user.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to disable MFA", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "MFA disabled", nil)
+}
+
+// Implementasi Verify (menukar token tantangan MFA + kode valid dengan access token)
+func (m *MFAServiceImpl) Verify(ctx *fiber.Ctx) utility.APIResponse {
+	var dto MFAVerifyDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := m.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	foundUser, claims, err := ParseMFAChallenge(m.DB, dto.MFAToken)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid or expired MFA token", nil)
+	}
+
+	// Kode pemulihan bisa dipakai sebagai pengganti kode TOTP
+	if !VerifyMFACode(m.DB, foundUser, dto.Code) {
+		RecordMFAFailure(claims, foundUser.Id)
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid code", nil)
+	}
+
+	ConsumeMFAChallenge(claims, foundUser.Id)
+
+	responseData, err := IssueSession(m.DB, foundUser)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create session", []string{err.Error()})
+	}
+	return utility.SuccessResponse(http.StatusOK, "Login success", responseData)
+}
+
+// Implementasi RegenerateRecoveryCodes (mengganti semua kode pemulihan, wajib kode TOTP valid)
+func (m *MFAServiceImpl) RegenerateRecoveryCodes(ctx *fiber.Ctx) utility.APIResponse {
+	var dto MFACodeDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := m.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	foundUser, response := m.currentUser(ctx)
+	if foundUser == nil {
+		return response
+	}
+
+	if !foundUser.MfaEnabled {
+		return utility.ErrorResponse(http.StatusBadRequest, "MFA is not enabled", nil)
+	}
+	if !checkTOTP(m.DB, *foundUser, dto.Code) {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid code", nil)
+	}
+
+	var recoveryCodes []string
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		codes, err := replaceRecoveryCodes(tx, foundUser.Id)
+		recoveryCodes = codes
+		return err
+	})
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to generate recovery codes", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Recovery codes regenerated", fiber.Map{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// currentUser mengambil user yang sedang login dari context
+func (m *MFAServiceImpl) currentUser(ctx *fiber.Ctx) (*user.User, utility.APIResponse) {
+	var foundUser user.User
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	if err := m.DB.First(&foundUser, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+	return &foundUser, utility.APIResponse{}
+}
+
+// VerifyMFACode memvalidasi kode TOTP atau kode pemulihan (yang langsung ditandai terpakai)
+func VerifyMFACode(db *gorm.DB, u user.User, code string) bool {
+	return checkTOTP(db, u, code) || useRecoveryCode(db, u.Id, code)
+}
+
+// checkTOTP mendekripsi secret user dan memvalidasi kode TOTP. Kode hanya diterima jika time step-nya
+// lebih baru dari kode terakhir yang diterima, sehingga kode yang sama tidak bisa dipakai dua kali.
+func checkTOTP(db *gorm.DB, u user.User, code string) bool {
+	if u.TotpSecret == nil {
+		return false
+	}
+	secret, err := helper.Decrypt(*u.TotpSecret)
+	if err != nil {
+		return false
+	}
+	step, valid := helper.ValidateTOTPStep(code, secret)
+	if !valid {
+		return false
+	}
+
+	// Update bersyarat agar dua request paralel dengan kode yang sama tidak sama-sama diterima
+	result := db.Model(&user.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", u.Id, step).
+		Update("totp_last_step", step)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// replaceRecoveryCodes menghapus kode pemulihan lama dan menyimpan hash kode baru
+func replaceRecoveryCodes(tx *gorm.DB, userID int64) ([]string, error) {
+	codes, err := helper.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&user.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]user.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		recoveryCodes = append(recoveryCodes, user.RecoveryCode{
+			UserId:   userID,
+			CodeHash: helper.HashToken(code),
+		})
+	}
+	if err := tx.Create(&recoveryCodes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// useRecoveryCode mencari kode pemulihan yang belum terpakai berdasarkan hash-nya lalu menandainya terpakai
+func useRecoveryCode(db *gorm.DB, userID int64, code string) bool {
+	code = helper.NormalizeRecoveryCode(code)
+	if code == "" {
+		return false
+	}
+
+	// Update bersyarat agar kode yang sama tidak bisa dipakai dua kali secara bersamaan
+	result := db.Model(&user.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, helper.HashToken(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// MFAMethods mengembalikan faktor kedua yang tersedia untuk user
+func MFAMethods(db *gorm.DB, u user.User) ([]string, error) {
+	var methods []string
+	if u.MfaEnabled {
+		methods = append(methods, "totp")
+	}
+
+	var credentialCount int64
+	if err := db.Model(&user.WebAuthnCredential{}).Where("user_id = ?", u.Id).Count(&credentialCount).Error; err != nil {
+		return nil, err
+	}
+	if credentialCount > 0 {
+		methods = append(methods, "webauthn")
+	}
+	return methods, nil
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// createMFAUser membuat user dengan TOTP aktif
+func createMFAUser(t *testing.T, db *gorm.DB) user.User {
+	t.Helper()
+	encryptedSecret, err := helper.Encrypt(testTOTPSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := user.User{Username: "budi", Role: user.USER, TotpSecret: &encryptedSecret, MfaEnabled: true}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+// currentTOTP kode TOTP yang berlaku sekarang untuk testTOTPSecret
+func currentTOTP(t *testing.T) string {
+	t.Helper()
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// postMFAVerify memanggil POST /auth/mfa/verify dan mengembalikan status beserta pesan respons
+func postMFAVerify(t *testing.T, app *fiber.App, mfaToken string, code string) (int, string) {
+	t.Helper()
+	payload, _ := json.Marshal(MFAVerifyDTO{MFAToken: mfaToken, Code: code})
+	request := httptest.NewRequest(http.MethodPost, "/auth/mfa/verify", bytes.NewReader(payload))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, body.Message
+}
+
+func newMFATestApp(db *gorm.DB) *fiber.App {
+	app := fiber.New()
+	app.Post("/auth/mfa/verify", NewMFAController(NewMFAService(db)).Verify)
+	return app
+}
+
+func TestCheckTOTPRejectsReplay(t *testing.T) {
+	db := newTestDB(t)
+	account := createMFAUser(t, db)
+	code := currentTOTP(t)
+
+	if !checkTOTP(db, account, code) {
+		t.Fatal("first use of a valid code was rejected")
+	}
+	if checkTOTP(db, account, code) {
+		t.Fatal("same code was accepted twice")
+	}
+}
+
+func TestMFAVerifyAcceptsValidCodeOnce(t *testing.T) {
+	db := newTestDB(t)
+	account := createMFAUser(t, db)
+	app := newMFATestApp(db)
+
+	mfaToken, err := helper.GeneratePurposeToken("mfa", account.Id, mfaChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := currentTOTP(t)
+
+	if status, message := postMFAVerify(t, app, mfaToken, code); status != http.StatusOK {
+		t.Fatalf("valid code: status %d (%s), want 200", status, message)
+	}
+
+	// Token tantangan dan kode TOTP sudah terpakai
+	secondToken, err := helper.GeneratePurposeToken("mfa", account.Id, mfaChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, message := postMFAVerify(t, app, secondToken, code); status != http.StatusUnauthorized || message != "Invalid code" {
+		t.Fatalf("replayed code: status %d (%s), want 401 Invalid code", status, message)
+	}
+	if status, _ := postMFAVerify(t, app, mfaToken, code); status != http.StatusUnauthorized {
+		t.Fatalf("reused challenge: status %d, want 401", status)
+	}
+}
+
+func TestMFAVerifyInvalidatesChallengeAfterMaxAttempts(t *testing.T) {
+	t.Setenv("MFA_MAX_ATTEMPTS", "3")
+	db := newTestDB(t)
+	account := createMFAUser(t, db)
+	app := newMFATestApp(db)
+
+	mfaToken, err := helper.GeneratePurposeToken("mfa", account.Id, mfaChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := currentTOTP(t)
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if status, message := postMFAVerify(t, app, mfaToken, wrongCode); status != http.StatusUnauthorized || message != "Invalid code" {
+			t.Fatalf("attempt %d: status %d (%s), want 401 Invalid code", attempt, status, message)
+		}
+	}
+
+	// Tantangan sudah dicabut, kode yang benar pun ditolak
+	if status, message := postMFAVerify(t, app, mfaToken, code); status != http.StatusUnauthorized || message != "Invalid or expired MFA token" {
+		t.Fatalf("after max attempts: status %d (%s), want 401 Invalid or expired MFA token", status, message)
+	}
+}
+
+func TestRecoveryCodeUsableOnce(t *testing.T) {
+	db := newTestDB(t)
+	account := createMFAUser(t, db)
+
+	codes, err := replaceRecoveryCodes(db, account.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	var stored user.RecoveryCode
+	db.Where("user_id = ?", account.Id).First(&stored)
+	if stored.CodeHash != helper.HashToken(codes[0]) {
+		t.Fatalf("code stored as %q, want SHA-256 of the code", stored.CodeHash)
+	}
+
+	// Input dinormalisasi: huruf besar dan spasi diabaikan
+	if !useRecoveryCode(db, account.Id, " "+strings.ToUpper(codes[0])+" ") {
+		t.Fatal("valid recovery code was rejected")
+	}
+	if useRecoveryCode(db, account.Id, codes[0]) {
+		t.Fatal("recovery code was accepted twice")
+	}
+	if useRecoveryCode(db, account.Id, "aaaa-aaaa-aaaa-aaaa") {
+		t.Fatal("unknown recovery code was accepted")
+	}
+}
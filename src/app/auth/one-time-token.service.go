@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+	PurposeMagicLink         = "magic_link"
+)
+
+var ErrOneTimeTokenInvalid = errors.New("invalid or expired token")
+
+// createOneTimeToken membatalkan token lama dengan tujuan yang sama lalu menyimpan hash token baru.
+// payload opsional (kosong berarti tidak ada) disimpan bersama token dan dicek ulang saat dipakai.
+func createOneTimeToken(db *gorm.DB, userID int64, purpose string, ttl time.Duration, payload string) (string, error) {
+	rawToken, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		token := OneTimeToken{
+			UserId:    userID,
+			Purpose:   purpose,
+			TokenHash: helper.HashToken(rawToken),
+			ExpiresAt: time.Now().Add(ttl),
+		}
+		if payload != "" {
+			token.Payload = &payload
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// consumeOneTimeToken menandai token terpakai; hanya berhasil sekali untuk token yang belum kedaluwarsa
+func consumeOneTimeToken(db *gorm.DB, rawToken string, purpose string) (OneTimeToken, error) {
+	var token OneTimeToken
+	if err := db.Where("token_hash = ? AND purpose = ?", helper.HashToken(rawToken), purpose).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, ErrOneTimeTokenInvalid
+		}
+		return token, err
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return token, ErrOneTimeTokenInvalid
+	}
+
+	// Update bersyarat agar token tidak bisa dipakai dua kali secara bersamaan
+	result := db.Model(&OneTimeToken{}).
+		Where("id = ? AND used_at IS NULL", token.Id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return token, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return token, ErrOneTimeTokenInvalid
+	}
+	return token, nil
+}
@@ -0,0 +1,21 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+type PasswordController struct {
+	Service PasswordService
+}
+
+func NewPasswordController(service PasswordService) *PasswordController {
+	return &PasswordController{Service: service}
+}
+
+func (pc *PasswordController) Forgot(ctx *fiber.Ctx) error {
+	response := pc.Service.Forgot(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (pc *PasswordController) Reset(ctx *fiber.Ctx) error {
+	response := pc.Service.Reset(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
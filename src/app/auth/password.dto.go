@@ -0,0 +1,10 @@
+package auth
+
+type ForgotPasswordDTO struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type ResetPasswordDTO struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"` // Aturan lain lewat helper.ValidatePassword
+}
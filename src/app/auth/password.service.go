@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// PasswordService interface
+type PasswordService interface {
+	Forgot(ctx *fiber.Ctx) utility.APIResponse
+	Reset(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// PasswordServiceImpl struct
+type PasswordServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Mailer   helper.Mailer
+}
+
+// Konstruktor untuk PasswordService
+func NewPasswordService(db *gorm.DB, mailer helper.Mailer) PasswordService {
+	return &PasswordServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+		Mailer:   mailer,
+	}
+}
+
+// errPasswordPolicy password baru ditolak password policy, untuk membatalkan transaksi reset
+var errPasswordPolicy = errors.New("password rejected by password policy")
+
+// passwordResetTTL masa berlaku token reset password (dalam menit), default 30 menit
+func passwordResetTTL() time.Duration {
+	return time.Minute * time.Duration(helper.GetEnvInt("PASSWORD_RESET_EXPIRATION", 30))
+}
+
+// Implementasi Forgot (mengirim token reset password).
+// Response selalu sama agar tidak membocorkan apakah username terdaftar.
+func (p *PasswordServiceImpl) Forgot(ctx *fiber.Ctx) utility.APIResponse {
+	var dto ForgotPasswordDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := p.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	response := utility.SuccessResponse(http.StatusOK, "If the account exists, a password reset link has been sent", nil)
+
+	var foundUser user.User
+	if err := p.DB.Where("username = ? AND is_active = ?", dto.Username, true).First(&foundUser).Error; err != nil {
+		return response
+	}
+
+	// Password user LDAP dikelola di direktori, bukan di sini; service account tidak punya password
+	if foundUser.AuthSource != user.AuthSourceLocal || foundUser.IsServiceAccount() {
+		return response
+	}
+
+	// Link reset hanya dikirim ke email yang sudah diverifikasi
+	if foundUser.Email == nil || foundUser.EmailVerifiedAt == nil {
+		return response
+	}
+
+	rawToken, err := createOneTimeToken(p.DB, foundUser.Id, PurposePasswordReset, passwordResetTTL(), "")
+	if err != nil {
+		log.Println("Gagal membuat token reset password:", err)
+		return response
+	}
+
+	// Kirim di background agar waktu response tidak membedakan user terdaftar / tidak
+	sendMailAsync(p.Mailer, helper.Mail{
+		To:      *foundUser.Email,
+		Subject: "Reset your password",
+		Body: "Use the link below to reset your password. The link expires in " +
+			passwordResetTTL().String() + " and can only be used once.\n\n" +
+			helper.GetEnv("APP_URL", "http://localhost:3000") + "/reset-password?token=" + rawToken +
+			"\n\nIf you did not request a password reset, you can ignore this email.",
+	})
+
+	return response
+}
+
+// Implementasi Reset (memakai token reset, mengganti password dan mencabut semua sesi)
+func (p *PasswordServiceImpl) Reset(ctx *fiber.Ctx) utility.APIResponse {
+	var dto ResetPasswordDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := p.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	// Dalam transaksi agar token tidak ikut terpakai jika password baru ditolak password policy
+	var token OneTimeToken
+	var policyErrors []string
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if token, err = consumeOneTimeToken(tx, dto.Token, PurposePasswordReset); err != nil {
+			return err
+		}
+
+		var owner user.User
+		if err := tx.Select("id", "username", "fullname", "email").First(&owner, token.UserId).Error; err != nil {
+			return err
+		}
+		if policyErrors = helper.ValidatePassword(dto.NewPassword, owner.PersonalInfo()...); len(policyErrors) > 0 {
+			return errPasswordPolicy
+		}
+
+		hashedPassword, err := helper.HashPassword(dto.NewPassword)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&user.User{}).Where("id = ?", owner.Id).Update("password", hashedPassword).Error
+	})
+	if err != nil {
+		switch err {
+		case ErrOneTimeTokenInvalid:
+			return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired reset token", nil)
+		case errPasswordPolicy:
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", policyErrors)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to reset password", []string{err.Error()})
+	}
+
+	// Semua sesi lama dicabut, user harus login ulang dengan password baru
+	if err := RevokeAllSessions(p.DB, token.UserId); err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to revoke sessions", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Password reset successfully", nil)
+}
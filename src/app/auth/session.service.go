@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/middleware"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// refreshTokenTTL membaca masa berlaku refresh token (dalam jam) dari env, default 30 hari
+func refreshTokenTTL() time.Duration {
+	return time.Hour * time.Duration(helper.GetEnvInt("REFRESH_TOKEN_EXPIRATION", 720))
+}
+
+// SessionOptions mengikat sesi ke client OAuth; nilai kosong berarti sesi first-party
+type SessionOptions struct {
+	ClientId string
+	Scope    string
+	FamilyId string // Kosong berarti family baru
+}
+
+// IssueSession membuat access token dan refresh token baru (family baru) untuk user
+func IssueSession(db *gorm.DB, u user.User) (LoginResponse, error) {
+	return IssueSessionWithOptions(db, u, SessionOptions{})
+}
+
+// IssueSessionWithOptions membuat sesi baru yang bisa diikat ke client OAuth dan scope tertentu
+func IssueSessionWithOptions(db *gorm.DB, u user.User, opts SessionOptions) (LoginResponse, error) {
+	if opts.FamilyId == "" {
+		opts.FamilyId = uuid.NewString()
+	}
+
+	refreshToken, err := createRefreshToken(db, u.Id, opts)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	return buildLoginResponse(u, refreshToken, opts)
+}
+
+// RotateRefreshToken menukar refresh token lama dengan pasangan token baru dalam family yang sama.
+// Token hanya bisa dirotasi oleh client yang menerimanya (clientID kosong untuk sesi first-party).
+// Jika token yang sudah dirotasi dipakai lagi, seluruh family dicabut.
+func RotateRefreshToken(db *gorm.DB, rawToken string, clientID string) (LoginResponse, error) {
+	var current RefreshToken
+	if err := db.Where("token_hash = ?", helper.HashToken(rawToken)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return LoginResponse{}, ErrRefreshTokenInvalid
+		}
+		return LoginResponse{}, err
+	}
+
+	opts := current.sessionOptions()
+	if opts.ClientId != clientID {
+		return LoginResponse{}, ErrRefreshTokenInvalid
+	}
+
+	if current.RotatedAt != nil {
+		if err := RevokeRefreshTokenFamily(db, current.FamilyId); err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{}, ErrRefreshTokenReused
+	}
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return LoginResponse{}, ErrRefreshTokenInvalid
+	}
+
+	var foundUser user.User
+	if err := db.First(&foundUser, current.UserId).Error; err != nil || !foundUser.IsActive {
+		_ = RevokeRefreshTokenFamily(db, current.FamilyId)
+		return LoginResponse{}, ErrRefreshTokenInvalid
+	}
+
+	var newToken string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Update bersyarat agar dua request bersamaan tidak bisa merotasi token yang sama
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.Id).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		token, err := createRefreshToken(tx, current.UserId, opts)
+		if err != nil {
+			return err
+		}
+		newToken = token
+		return nil
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		_ = RevokeRefreshTokenFamily(db, current.FamilyId)
+		return LoginResponse{}, err
+	}
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return buildLoginResponse(foundUser, newToken, opts)
+}
+
+// RateLimitByRefreshFamily key rate limit berdasarkan family dari refresh token di body request, sehingga
+// client berbeda di balik IP (NAT) yang sama tidak saling membatasi. Token yang tidak dikenal tidak diberi key.
+func RateLimitByRefreshFamily(db *gorm.DB) middleware.RateLimitKeyFunc {
+	return func(ctx *fiber.Ctx) string {
+		current := refreshTokenFromBody(ctx, db, "family_id")
+		if current == nil {
+			return ""
+		}
+		return "family:" + current.FamilyId
+	}
+}
+
+// RateLimitByRefreshClient key rate limit berdasarkan client pemegang refresh token di body request, atau user
+// pemiliknya untuk sesi first-party (format key sama dengan middleware.RateLimitByClient)
+func RateLimitByRefreshClient(db *gorm.DB) middleware.RateLimitKeyFunc {
+	return func(ctx *fiber.Ctx) string {
+		current := refreshTokenFromBody(ctx, db, "user_id", "client_id")
+		if current == nil {
+			return ""
+		}
+		if current.ClientId != nil {
+			return "client:" + *current.ClientId
+		}
+		return "user:" + strconv.FormatInt(current.UserId, 10)
+	}
+}
+
+// refreshTokenFromBody mencari refresh token dari body request (hanya kolom yang diminta), nil jika tidak dikenal
+func refreshTokenFromBody(ctx *fiber.Ctx, db *gorm.DB, columns ...string) *RefreshToken {
+	var dto RefreshTokenDTO
+	if err := ctx.BodyParser(&dto); err != nil || dto.RefreshToken == "" {
+		return nil
+	}
+
+	var current RefreshToken
+	if err := db.Select(columns).Where("token_hash = ?", helper.HashToken(dto.RefreshToken)).First(&current).Error; err != nil {
+		return nil
+	}
+	return &current
+}
+
+// RevokeRefreshTokenFamily mencabut semua refresh token yang masih aktif dalam satu family
+func RevokeRefreshTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeRefreshToken mencabut family dari refresh token milik user tertentu
+func RevokeRefreshToken(db *gorm.DB, userID int64, rawToken string) error {
+	var current RefreshToken
+	if err := db.Where("token_hash = ? AND user_id = ?", helper.HashToken(rawToken), userID).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		return err
+	}
+	return RevokeRefreshTokenFamily(db, current.FamilyId)
+}
+
+// RevokeAllSessions mencabut semua refresh token dan access token milik user
+func RevokeAllSessions(db *gorm.DB, userID int64) error {
+	if err := db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return helper.RevokeUserTokens(userID)
+}
+
+// createRefreshToken menyimpan hash refresh token baru dan mengembalikan token mentahnya
+func createRefreshToken(db *gorm.DB, userID int64, opts SessionOptions) (string, error) {
+	rawToken, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := RefreshToken{
+		UserId:    userID,
+		FamilyId:  opts.FamilyId,
+		TokenHash: helper.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}
+	if opts.ClientId != "" {
+		refreshToken.ClientId = &opts.ClientId
+	}
+	if opts.Scope != "" {
+		refreshToken.Scope = &opts.Scope
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// sessionOptions mengembalikan client dan scope yang terikat pada refresh token
+func (r RefreshToken) sessionOptions() SessionOptions {
+	opts := SessionOptions{FamilyId: r.FamilyId}
+	if r.ClientId != nil {
+		opts.ClientId = *r.ClientId
+	}
+	if r.Scope != nil {
+		opts.Scope = *r.Scope
+	}
+	return opts
+}
+
+// buildLoginResponse membuat access token JWT dan menyusun LoginResponse
+func buildLoginResponse(u user.User, refreshToken string, opts SessionOptions) (LoginResponse, error) {
+	fullname := ""
+	if u.Fullname != nil {
+		fullname = *u.Fullname
+	}
+
+	var (
+		token string
+		err   error
+	)
+	if opts.ClientId != "" {
+		token, err = helper.GenerateDelegatedJWT(u.Id, u.Username, fullname, string(u.Role), opts.ClientId, opts.Scope)
+	} else {
+		token, err = helper.GenerateJWT(u.Id, u.Username, fullname, string(u.Role))
+	}
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Id:           u.Id,
+		Username:     u.Username,
+		Fullname:     fullname,
+		Role:         string(u.Role),
+		Token:        token,
+		RefreshToken: refreshToken,
+		Scope:        opts.Scope,
+	}, nil
+}
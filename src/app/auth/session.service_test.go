@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// createSessionUser membuat user aktif untuk test sesi
+func createSessionUser(t *testing.T, db *gorm.DB) user.User {
+	t.Helper()
+	account := user.User{Username: "siti", Role: user.USER, IsActive: true}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+// findRefreshToken baris refresh token berdasarkan token mentahnya
+func findRefreshToken(t *testing.T, db *gorm.DB, rawToken string) RefreshToken {
+	t.Helper()
+	var token RefreshToken
+	if err := db.Where("token_hash = ?", helper.HashToken(rawToken)).First(&token).Error; err != nil {
+		t.Fatalf("find refresh token: %v", err)
+	}
+	return token
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	session, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	rotated, err := RotateRefreshToken(db, session.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if rotated.RefreshToken == "" || rotated.RefreshToken == session.RefreshToken {
+		t.Fatal("rotation did not return a new refresh token")
+	}
+	if rotated.Token == "" || rotated.Id != account.Id {
+		t.Fatalf("unexpected login response %+v", rotated)
+	}
+
+	previous := findRefreshToken(t, db, session.RefreshToken)
+	next := findRefreshToken(t, db, rotated.RefreshToken)
+	if previous.RotatedAt == nil {
+		t.Error("old refresh token is not marked as rotated")
+	}
+	if next.FamilyId != previous.FamilyId {
+		t.Errorf("new token family = %q, want %q", next.FamilyId, previous.FamilyId)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	session, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	rotated, err := RotateRefreshToken(db, session.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	other, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+
+	// Token lama dipakai lagi (misal dicuri): seluruh family dicabut
+	if _, err := RotateRefreshToken(db, session.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := RotateRefreshToken(db, rotated.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("rotating the newest token after reuse: error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if token := findRefreshToken(t, db, rotated.RefreshToken); token.RevokedAt == nil {
+		t.Error("newest token in the family is not revoked")
+	}
+
+	// Family lain milik user yang sama tidak terpengaruh
+	if _, err := RotateRefreshToken(db, other.RefreshToken, ""); err != nil {
+		t.Errorf("rotating an unrelated family: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenClientBinding(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	session, err := IssueSessionWithOptions(db, account, SessionOptions{ClientId: "mobile-app", Scope: "openid profile"})
+	if err != nil {
+		t.Fatalf("IssueSessionWithOptions: %v", err)
+	}
+	for _, clientID := range []string{"", "other-app"} {
+		if _, err := RotateRefreshToken(db, session.RefreshToken, clientID); !errors.Is(err, ErrRefreshTokenInvalid) {
+			t.Errorf("rotate as client %q: error = %v, want %v", clientID, err, ErrRefreshTokenInvalid)
+		}
+	}
+
+	// Percobaan dari client lain tidak menghabiskan token milik client yang benar
+	rotated, err := RotateRefreshToken(db, session.RefreshToken, "mobile-app")
+	if err != nil {
+		t.Fatalf("rotate as owning client: %v", err)
+	}
+	if rotated.Scope != "openid profile" {
+		t.Errorf("scope = %q, want %q", rotated.Scope, "openid profile")
+	}
+	if next := findRefreshToken(t, db, rotated.RefreshToken); next.ClientId == nil || *next.ClientId != "mobile-app" {
+		t.Error("rotated token is not bound to the client")
+	}
+}
+
+func TestRotateRefreshTokenRejectsInvalid(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	if _, err := RotateRefreshToken(db, "unknown-token", ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("unknown token: error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	expired, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	db.Model(&RefreshToken{}).Where("token_hash = ?", helper.HashToken(expired.RefreshToken)).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := RotateRefreshToken(db, expired.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("expired token: error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	session, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	db.Model(&user.User{}).Where("id = ?", account.Id).Update("is_active", false)
+	if _, err := RotateRefreshToken(db, session.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("inactive user: error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if token := findRefreshToken(t, db, session.RefreshToken); token.RevokedAt == nil {
+		t.Error("family of an inactive user is not revoked")
+	}
+}
+
+func TestRefreshRateLimitKeys(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	session, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	clientSession, err := IssueSessionWithOptions(db, account, SessionOptions{ClientId: "reporting", Scope: "openid"})
+	if err != nil {
+		t.Fatalf("IssueSessionWithOptions: %v", err)
+	}
+	family := findRefreshToken(t, db, session.RefreshToken).FamilyId
+	userKey := "user:" + strconv.FormatInt(account.Id, 10)
+
+	app := fiber.New()
+	app.Post("/family", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(RateLimitByRefreshFamily(db)(ctx))
+	})
+	app.Post("/client", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(RateLimitByRefreshClient(db)(ctx))
+	})
+
+	cases := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{"family of known token", "/family", `{"refresh_token":"` + session.RefreshToken + `"}`, "family:" + family},
+		{"family of unknown token", "/family", `{"refresh_token":"unknown"}`, ""},
+		{"family without token", "/family", `{}`, ""},
+		{"family with malformed body", "/family", `{`, ""},
+		{"first-party session", "/client", `{"refresh_token":"` + session.RefreshToken + `"}`, userKey},
+		{"client session", "/client", `{"refresh_token":"` + clientSession.RefreshToken + `"}`, "client:reporting"},
+		{"client of unknown token", "/client", `{"refresh_token":"unknown"}`, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		key, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(key) != tc.want {
+			t.Errorf("%s: key = %q, want %q", tc.name, key, tc.want)
+		}
+	}
+}
+
+func TestDeprecatedRefreshRequiresRefreshToken(t *testing.T) {
+	db := newTestDB(t)
+	account := createSessionUser(t, db)
+
+	session, err := IssueSession(db, account)
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/auth/refresh", NewAuthController(NewAuthService(db, nil)).RefreshToken)
+
+	cases := []struct {
+		name          string
+		authorization string
+		body          string
+		want          int
+	}{
+		{"access token only", "Bearer " + session.Token, "", http.StatusBadRequest},
+		{"refresh token", "", `{"refresh_token":"` + session.RefreshToken + `"}`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/auth/refresh", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+		if resp.Header.Get("Deprecation") != "true" {
+			t.Errorf("%s: missing Deprecation header", tc.name)
+		}
+	}
+}
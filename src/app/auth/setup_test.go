@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.SetSecrets()
+	os.Exit(m.Run())
+}
+
+// newTestDB database SQLite in-memory dengan tabel milik paket auth dan store pencabutan token
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := testutil.NewDB(t, &RefreshToken{}, &OneTimeToken{}, &AuditLog{}, &LoginFailure{},
+		&user.RecoveryCode{}, &user.WebAuthnCredential{}, &helper.RevokedToken{}, &helper.UserTokenRevocation{})
+	store := helper.SetupRevocationStore(db)
+	t.Cleanup(store.Close)
+	return db
+}
@@ -0,0 +1,29 @@
+package federation
+
+import "github.com/gofiber/fiber/v2"
+
+type FederationController struct {
+	Service FederationService
+}
+
+func NewFederationController(service FederationService) *FederationController {
+	return &FederationController{Service: service}
+}
+
+func (fc *FederationController) Providers(ctx *fiber.Ctx) error {
+	response := fc.Service.Providers(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (fc *FederationController) Login(ctx *fiber.Ctx) error {
+	redirectURL, response := fc.Service.Login(ctx)
+	if redirectURL == "" {
+		return ctx.Status(response.Status).JSON(response)
+	}
+	return ctx.Redirect(redirectURL, fiber.StatusFound)
+}
+
+func (fc *FederationController) Callback(ctx *fiber.Ctx) error {
+	response := fc.Service.Callback(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
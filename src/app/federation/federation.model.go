@@ -0,0 +1,51 @@
+package federation
+
+import (
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// Provider konfigurasi identity provider OIDC upstream (Google, Azure AD, GitLab, dll.)
+type Provider struct {
+	Name          string // Dipakai di URL, misal "google"
+	DisplayName   string // Ditampilkan di UI, misal "Google"
+	Issuer        string // URL issuer untuk discovery
+	ClientId      string
+	ClientSecret  string
+	Scopes        []string          // Default "openid profile email"
+	RoleClaim     string            // Claim berisi grup / role, misal "groups"
+	RoleMap       map[string]string // Nilai claim -> role lokal, misal "platform-admins" -> "admin"
+	DefaultRole   string            // Role untuk user baru jika tidak ada yang cocok
+	AutoProvision bool              // Buat user baru otomatis saat login pertama
+	LinkByEmail   bool              // Hubungkan ke user lokal dengan email terverifikasi yang sama
+
+	mu   sync.Mutex
+	oidc *oidc.Provider // Hasil discovery, dimuat saat pertama dipakai
+}
+
+// ProviderInfo data provider yang aman ditampilkan ke client
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// flowState disimpan terenkripsi di cookie antara redirect ke provider dan callback
+type flowState struct {
+	Provider  string `json:"provider"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// identityClaims claims dari ID token provider yang dipakai untuk provisioning
+type identityClaims struct {
+	Subject           string      `json:"sub"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // Sebagian provider mengirim string "true"
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+	Nonce             string      `json:"nonce"`
+}
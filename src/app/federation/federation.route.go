@@ -0,0 +1,17 @@
+package federation
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SetupRoutes mengatur routing login federasi lewat identity provider OIDC upstream
+func SetupRoutes(app *fiber.App, db *gorm.DB) {
+	federationService := NewFederationService(db)
+	federationController := NewFederationController(federationService)
+
+	federationRoutes := app.Group("/auth/federated")
+	federationRoutes.Get("/providers", federationController.Providers)
+	federationRoutes.Get("/:provider/login", federationController.Login)
+	federationRoutes.Get("/:provider/callback", federationController.Callback)
+}
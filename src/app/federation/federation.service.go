@@ -0,0 +1,464 @@
+package federation
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+const (
+	// stateCookie menyimpan state, nonce dan PKCE verifier selama redirect ke provider
+	stateCookie = "federation_state"
+	// flowTTL batas waktu user menyelesaikan login di provider
+	flowTTL = 10 * time.Minute
+	// providerTimeout batas waktu request ke provider (discovery, token exchange)
+	providerTimeout = 10 * time.Second
+)
+
+// rolePriority urutan role saat beberapa grup cocok, role paling tinggi dipilih
+var rolePriority = []user.Role{user.ADMIN, user.USER}
+
+// usernamePattern karakter yang diizinkan untuk username hasil provisioning
+var usernamePattern = regexp.MustCompile(`[^a-zA-Z0-9._@-]+`)
+
+// FederationService interface
+type FederationService interface {
+	Providers(ctx *fiber.Ctx) utility.APIResponse
+	Login(ctx *fiber.Ctx) (string, utility.APIResponse)
+	Callback(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// FederationServiceImpl struct
+type FederationServiceImpl struct {
+	DB         *gorm.DB
+	Configured map[string]*Provider
+	Order      []string
+}
+
+// Konstruktor untuk FederationService, provider dibaca dari env FEDERATION_*
+func NewFederationService(db *gorm.DB) FederationService {
+	service := &FederationServiceImpl{DB: db, Configured: map[string]*Provider{}}
+
+	for _, name := range strings.Split(os.Getenv("FEDERATION_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		provider, err := loadProvider(name)
+		if err != nil {
+			log.Fatal("Konfigurasi identity provider tidak valid:", err)
+		}
+		service.Configured[name] = provider
+		service.Order = append(service.Order, name)
+	}
+	return service
+}
+
+// loadProvider membaca konfigurasi provider dari env dengan prefix FEDERATION_<NAMA>_
+func loadProvider(name string) (*Provider, error) {
+	prefix := "FEDERATION_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+	provider := &Provider{
+		Name:          name,
+		DisplayName:   helper.GetEnv(prefix+"DISPLAY_NAME", name),
+		Issuer:        os.Getenv(prefix + "ISSUER"),
+		ClientId:      os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+		Scopes:        strings.Fields(helper.GetEnv(prefix+"SCOPES", "openid profile email")),
+		RoleClaim:     os.Getenv(prefix + "ROLE_CLAIM"),
+		RoleMap:       map[string]string{},
+		DefaultRole:   helper.GetEnv(prefix+"DEFAULT_ROLE", string(user.USER)),
+		AutoProvision: helper.GetEnvBool(prefix+"AUTO_PROVISION", false),
+		LinkByEmail:   helper.GetEnvBool(prefix+"LINK_BY_EMAIL", false),
+	}
+
+	if provider.Issuer == "" || provider.ClientId == "" {
+		return nil, fmt.Errorf("%sISSUER dan %sCLIENT_ID wajib diisi", prefix, prefix)
+	}
+	if !validRole(provider.DefaultRole) {
+		return nil, fmt.Errorf("%sDEFAULT_ROLE tidak valid: %s", prefix, provider.DefaultRole)
+	}
+
+	// Format: "grup-a=admin,grup-b=user"
+	for _, pair := range strings.Split(os.Getenv(prefix+"ROLE_MAP"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		if !ok || !validRole(role) {
+			return nil, fmt.Errorf("%sROLE_MAP tidak valid: %s", prefix, pair)
+		}
+		provider.RoleMap[strings.TrimSpace(group)] = role
+	}
+	return provider, nil
+}
+
+// Implementasi Providers (daftar provider yang bisa dipakai untuk login)
+func (f *FederationServiceImpl) Providers(ctx *fiber.Ctx) utility.APIResponse {
+	providers := make([]ProviderInfo, 0, len(f.Order))
+	for _, name := range f.Order {
+		providers = append(providers, ProviderInfo{
+			Name:        name,
+			DisplayName: f.Configured[name].DisplayName,
+			LoginURL:    "/auth/federated/" + name + "/login",
+		})
+	}
+	return utility.SuccessResponse(http.StatusOK, "OK", providers)
+}
+
+// Implementasi Login (mengarahkan user ke halaman login provider dengan state, nonce dan PKCE)
+func (f *FederationServiceImpl) Login(ctx *fiber.Ctx) (string, utility.APIResponse) {
+	provider, ok := f.Configured[ctx.Params("provider")]
+	if !ok {
+		return "", utility.ErrorResponse(http.StatusNotFound, "Unknown identity provider", nil)
+	}
+
+	config, _, err := provider.client()
+	if err != nil {
+		return "", utility.ErrorResponse(http.StatusBadGateway, "Identity provider is unavailable", []string{err.Error()})
+	}
+
+	state, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", utility.ErrorResponse(http.StatusInternalServerError, "Failed to start login", []string{err.Error()})
+	}
+	nonce, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", utility.ErrorResponse(http.StatusInternalServerError, "Failed to start login", []string{err.Error()})
+	}
+
+	flow := flowState{
+		Provider:  provider.Name,
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(flowTTL).Unix(),
+	}
+	payload, _ := json.Marshal(flow)
+	encrypted, err := helper.Encrypt(string(payload))
+	if err != nil {
+		return "", utility.ErrorResponse(http.StatusInternalServerError, "Failed to start login", []string{err.Error()})
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     stateCookie,
+		Value:    encrypted,
+		Path:     "/auth/federated",
+		Expires:  time.Now().Add(flowTTL),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(flow.Verifier)), utility.APIResponse{}
+}
+
+// Implementasi Callback (menukar kode, memverifikasi ID token, lalu login / provisioning user lokal)
+func (f *FederationServiceImpl) Callback(ctx *fiber.Ctx) utility.APIResponse {
+	provider, ok := f.Configured[ctx.Params("provider")]
+	if !ok {
+		return utility.ErrorResponse(http.StatusNotFound, "Unknown identity provider", nil)
+	}
+
+	if errorCode := ctx.Query("error"); errorCode != "" {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Identity provider returned an error", []string{errorCode, ctx.Query("error_description")})
+	}
+
+	// State cookie hanya berlaku sekali
+	flow, err := readFlowState(ctx.Cookies(stateCookie))
+	ctx.Cookie(&fiber.Cookie{Name: stateCookie, Value: "", Path: "/auth/federated", Expires: time.Unix(0, 0), HTTPOnly: true})
+	if err != nil || flow.Provider != provider.Name || time.Now().Unix() > flow.ExpiresAt ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(ctx.Query("state"))) != 1 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired login state", nil)
+	}
+
+	code := ctx.Query("code")
+	if code == "" {
+		return utility.ErrorResponse(http.StatusBadRequest, "Missing authorization code", nil)
+	}
+
+	config, verifier, err := provider.client()
+	if err != nil {
+		return utility.ErrorResponse(http.StatusBadGateway, "Identity provider is unavailable", []string{err.Error()})
+	}
+
+	requestCtx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	token, err := config.Exchange(requestCtx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Failed to exchange authorization code", []string{err.Error()})
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Identity provider did not return an ID token", nil)
+	}
+	idToken, err := verifier.Verify(requestCtx, rawIDToken)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid ID token", []string{err.Error()})
+	}
+
+	var identity identityClaims
+	var rawClaims map[string]interface{}
+	if err := idToken.Claims(&identity); err != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid ID token claims", []string{err.Error()})
+	}
+	if err := idToken.Claims(&rawClaims); err != nil {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid ID token claims", []string{err.Error()})
+	}
+	if subtle.ConstantTimeCompare([]byte(identity.Nonce), []byte(flow.Nonce)) != 1 {
+		return utility.ErrorResponse(http.StatusUnauthorized, "Invalid ID token nonce", nil)
+	}
+
+	foundUser, response := f.resolveUser(provider, identity, rawClaims)
+	if foundUser == nil {
+		return response
+	}
+
+	// Generate access token & refresh token (atau tantangan MFA jika aktif)
+	return auth.CompleteLogin(f.DB, *foundUser)
+}
+
+// resolveUser mencari user lewat identitas yang sudah terhubung, menghubungkan lewat email
+// terverifikasi, atau membuat user baru (just-in-time provisioning) sesuai konfigurasi provider
+func (f *FederationServiceImpl) resolveUser(provider *Provider, identity identityClaims, rawClaims map[string]interface{}) (*user.User, utility.APIResponse) {
+	now := time.Now()
+	email := helper.NormalizeEmail(identity.Email)
+	emailVerified := isTrue(identity.EmailVerified)
+	role := provider.mapRole(rawClaims)
+
+	var linked user.UserIdentity
+	err := f.DB.Where("provider = ? AND subject = ?", provider.Name, identity.Subject).First(&linked).Error
+	if err == nil {
+		var foundUser user.User
+		if err := f.DB.First(&foundUser, linked.UserId).Error; err != nil {
+			return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+		}
+		if !foundUser.IsActive {
+			return nil, utility.ErrorResponse(http.StatusForbidden, "Account is disabled", nil)
+		}
+
+		// Role disinkronkan setiap login jika provider mengirim claim grup
+		updates := map[string]interface{}{"last_login_at": now}
+		if email != "" {
+			updates["email"] = email
+		}
+		f.DB.Model(&linked).Updates(updates)
+		if role != "" && foundUser.Role != user.Role(role) {
+			if err := f.DB.Model(&foundUser).Update("role", role).Error; err != nil {
+				return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to update user", []string{err.Error()})
+			}
+		}
+		return &foundUser, utility.APIResponse{}
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve identity", []string{err.Error()})
+	}
+
+	newIdentity := user.UserIdentity{
+		Provider:    provider.Name,
+		Subject:     identity.Subject,
+		LastLoginAt: &now,
+	}
+	if email != "" {
+		newIdentity.Email = &email
+	}
+
+	// Hubungkan ke user lokal hanya jika email terverifikasi di kedua sisi, agar akun tidak bisa diambil alih
+	if provider.LinkByEmail && email != "" && emailVerified {
+		var foundUser user.User
+		if f.DB.Where("email = ? AND email_verified_at IS NOT NULL", email).First(&foundUser).Error == nil {
+			if !foundUser.IsActive {
+				return nil, utility.ErrorResponse(http.StatusForbidden, "Account is disabled", nil)
+			}
+			newIdentity.UserId = foundUser.Id
+			if err := f.DB.Create(&newIdentity).Error; err != nil {
+				return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to link identity", []string{err.Error()})
+			}
+			return &foundUser, utility.APIResponse{}
+		}
+	}
+
+	if !provider.AutoProvision {
+		return nil, utility.ErrorResponse(http.StatusForbidden, "No account is linked to this identity", nil)
+	}
+
+	username, err := f.uniqueUsername(identity)
+	if err != nil {
+		return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+	}
+	if role == "" {
+		role = provider.DefaultRole
+	}
+
+	newUser := user.User{
+		Username: username,
+		Role:     user.Role(role),
+		IsActive: true,
+	}
+	if identity.Name != "" {
+		newUser.Fullname = &identity.Name
+	}
+	// Email hanya disimpan jika belum dipakai user lain
+	if email != "" {
+		if taken, err := user.EmailTaken(f.DB, email, 0); err == nil && !taken {
+			newUser.Email = &email
+			if emailVerified {
+				newUser.EmailVerifiedAt = &now
+			}
+		}
+	}
+
+	err = f.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		newIdentity.UserId = newUser.Id
+		return tx.Create(&newIdentity).Error
+	})
+	if err != nil {
+		return nil, utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+	}
+	return &newUser, utility.APIResponse{}
+}
+
+// uniqueUsername membuat username dari claims provider, ditambah angka jika sudah dipakai
+func (f *FederationServiceImpl) uniqueUsername(identity identityClaims) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" {
+		base = identity.Email
+	}
+	base = usernamePattern.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user-" + usernamePattern.ReplaceAllString(identity.Subject, "")
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := f.DB.Model(&user.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// client mengembalikan konfigurasi OAuth2 dan verifier ID token; discovery dilakukan saat pertama dipakai
+func (p *Provider) client() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oidc == nil {
+		requestCtx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		defer cancel()
+
+		discovered, err := oidc.NewProvider(requestCtx, p.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.oidc = discovered
+	}
+
+	config := &oauth2.Config{
+		ClientID:     p.ClientId,
+		ClientSecret: p.ClientSecret,
+		Endpoint:     p.oidc.Endpoint(),
+		RedirectURL:  strings.TrimRight(helper.GetEnv("APP_URL", "http://localhost:3000"), "/") + "/auth/federated/" + p.Name + "/callback",
+		Scopes:       p.Scopes,
+	}
+	return config, p.oidc.Verifier(&oidc.Config{ClientID: p.ClientId}), nil
+}
+
+// mapRole memetakan nilai claim grup ke role lokal; kosong jika claim tidak dikonfigurasi / tidak ada yang cocok
+func (p *Provider) mapRole(claims map[string]interface{}) string {
+	if p.RoleClaim == "" || len(p.RoleMap) == 0 {
+		return ""
+	}
+
+	var values []string
+	switch value := claims[p.RoleClaim].(type) {
+	case string:
+		values = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	matched := map[string]bool{}
+	for _, value := range values {
+		if role, ok := p.RoleMap[value]; ok {
+			matched[role] = true
+		}
+	}
+	for _, role := range rolePriority {
+		if matched[string(role)] {
+			return string(role)
+		}
+	}
+	return p.DefaultRole
+}
+
+// readFlowState mendekripsi isi cookie state
+func readFlowState(cookie string) (flowState, error) {
+	var flow flowState
+	if cookie == "" {
+		return flow, fmt.Errorf("missing state cookie")
+	}
+
+	payload, err := helper.Decrypt(cookie)
+	if err != nil {
+		return flow, err
+	}
+	err = json.Unmarshal([]byte(payload), &flow)
+	return flow, err
+}
+
+// validRole mengecek apakah role dikenal
+func validRole(role string) bool {
+	for _, r := range rolePriority {
+		if string(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrue membaca claim boolean yang bisa dikirim sebagai bool atau string
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
@@ -0,0 +1,363 @@
+package federation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+const (
+	testClientID     = "go-auth-test"
+	testClientSecret = "secret"
+)
+
+// fakeProvider identity provider OIDC palsu: discovery, JWKS, dan token endpoint dengan PKCE S256
+type fakeProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+// issuedCode kode otorisasi beserta claims ID token yang akan diterbitkan untuknya
+type issuedCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &fakeProvider{key: key, codes: map[string]issuedCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                provider.server.URL,
+			"authorization_endpoint":                provider.server.URL + "/authorize",
+			"token_endpoint":                        provider.server.URL + "/token",
+			"jwks_uri":                              provider.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", provider.token)
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// token menukar kode dengan ID token setelah memeriksa client secret dan code_verifier
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	issued, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || base64.RawURLEncoding.EncodeToString(sum[:]) != issued.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{"iss": p.server.URL, "aud": testClientID, "iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+	for key, value := range issued.claims {
+		claims[key] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// authorize mensimulasikan user login di provider: mengembalikan kode untuk redirect authorize
+func (p *fakeProvider) authorize(t *testing.T, authorizeURL string, claims jwt.MapClaims) (code string, state string) {
+	t.Helper()
+	parsed, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorize request %s", authorizeURL)
+	}
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+
+	code = "code-" + query.Get("state")[:8]
+	p.mu.Lock()
+	p.codes[code] = issuedCode{challenge: query.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// federationTestApp service dengan satu provider "test" yang mengarah ke fakeProvider
+func federationTestApp(t *testing.T, configure func(*Provider)) (*fiber.App, *gorm.DB, *fakeProvider) {
+	t.Helper()
+	db := testutil.NewDB(t, &user.UserIdentity{}, &user.WebAuthnCredential{}, &auth.RefreshToken{})
+	fake := newFakeProvider(t)
+
+	provider := &Provider{
+		Name:         "test",
+		DisplayName:  "Test",
+		Issuer:       fake.server.URL,
+		ClientId:     testClientID,
+		ClientSecret: testClientSecret,
+		Scopes:       []string{"openid", "profile", "email"},
+		RoleMap:      map[string]string{},
+		DefaultRole:  string(user.USER),
+	}
+	if configure != nil {
+		configure(provider)
+	}
+
+	service := &FederationServiceImpl{DB: db, Configured: map[string]*Provider{"test": provider}, Order: []string{"test"}}
+	controller := NewFederationController(service)
+	app := fiber.New()
+	app.Get("/auth/federated/:provider/login", controller.Login)
+	app.Get("/auth/federated/:provider/callback", controller.Callback)
+	return app, db, fake
+}
+
+// startLogin memanggil endpoint login dan mengembalikan URL authorize provider beserta cookie state
+func startLogin(t *testing.T, app *fiber.App) (string, *http.Cookie) {
+	t.Helper()
+	response, err := app.Test(httptest.NewRequest(http.MethodGet, "/auth/federated/test/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("login: status %d, want 302", response.StatusCode)
+	}
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == stateCookie {
+			return response.Header.Get("Location"), cookie
+		}
+	}
+	t.Fatal("login did not set the state cookie")
+	return "", nil
+}
+
+// callback memanggil endpoint callback seperti browser yang kembali dari provider
+func callback(t *testing.T, app *fiber.App, code string, state string, cookie *http.Cookie) (int, string) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/auth/federated/test/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	response, err := app.Test(request, 15000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(response.Body).Decode(&body)
+	return response.StatusCode, body.Message
+}
+
+// signIn menjalankan alur login lengkap dengan claims ID token tertentu
+func signIn(t *testing.T, app *fiber.App, fake *fakeProvider, claims jwt.MapClaims) (int, string) {
+	t.Helper()
+	authorizeURL, cookie := startLogin(t, app)
+	code, state := fake.authorize(t, authorizeURL, claims)
+	return callback(t, app, code, state, cookie)
+}
+
+func TestCallbackProvisionsUser(t *testing.T) {
+	app, db, fake := federationTestApp(t, func(p *Provider) {
+		p.AutoProvision = true
+		p.RoleClaim = "groups"
+		p.RoleMap = map[string]string{"platform-admins": "admin"}
+	})
+
+	status, message := signIn(t, app, fake, jwt.MapClaims{
+		"sub":                "subject-1",
+		"email":              "Budi@Example.com",
+		"email_verified":     true,
+		"name":               "Budi Santoso",
+		"preferred_username": "budi",
+		"groups":             []string{"staff", "platform-admins"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("callback: status %d (%s), want 200", status, message)
+	}
+
+	var created user.User
+	if err := db.Where("username = ?", "budi").First(&created).Error; err != nil {
+		t.Fatalf("user not provisioned: %v", err)
+	}
+	if created.Role != user.ADMIN || created.Email == nil || *created.Email != "budi@example.com" || created.EmailVerifiedAt == nil {
+		t.Errorf("provisioned user = role %s email %v verified %v", created.Role, created.Email, created.EmailVerifiedAt)
+	}
+	var identity user.UserIdentity
+	if err := db.Where("provider = ? AND subject = ?", "test", "subject-1").First(&identity).Error; err != nil || identity.UserId != created.Id {
+		t.Errorf("identity = %+v (err %v), want linked to user %d", identity, err, created.Id)
+	}
+
+	// Login berikutnya memakai identitas yang sudah terhubung, tidak membuat user baru
+	if status, message := signIn(t, app, fake, jwt.MapClaims{"sub": "subject-1", "preferred_username": "budi"}); status != http.StatusOK {
+		t.Fatalf("second login: status %d (%s)", status, message)
+	}
+	var count int64
+	db.Model(&user.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("users after second login = %d, want 1", count)
+	}
+}
+
+func TestCallbackRejectsInvalidState(t *testing.T) {
+	app, _, fake := federationTestApp(t, func(p *Provider) { p.AutoProvision = true })
+	claims := jwt.MapClaims{"sub": "subject-1"}
+
+	authorizeURL, cookie := startLogin(t, app)
+	code, _ := fake.authorize(t, authorizeURL, claims)
+	if status, message := callback(t, app, code, "forged-state", cookie); status != http.StatusBadRequest || message != "Invalid or expired login state" {
+		t.Errorf("forged state: status %d (%s), want 400", status, message)
+	}
+
+	authorizeURL, _ = startLogin(t, app)
+	code, state := fake.authorize(t, authorizeURL, jwt.MapClaims{"sub": "subject-1"})
+	if status, _ := callback(t, app, code, state, nil); status != http.StatusBadRequest {
+		t.Errorf("missing state cookie: status %d, want 400", status)
+	}
+
+	// Cookie dari alur login lain tidak bisa dipakai untuk state ini
+	_, otherCookie := startLogin(t, app)
+	authorizeURL, _ = startLogin(t, app)
+	code, state = fake.authorize(t, authorizeURL, jwt.MapClaims{"sub": "subject-1"})
+	if status, _ := callback(t, app, code, state, otherCookie); status != http.StatusBadRequest {
+		t.Errorf("cookie from another flow: status %d, want 400", status)
+	}
+}
+
+func TestCallbackRejectsNonceMismatch(t *testing.T) {
+	app, db, fake := federationTestApp(t, func(p *Provider) { p.AutoProvision = true })
+
+	status, message := signIn(t, app, fake, jwt.MapClaims{"sub": "subject-1", "preferred_username": "budi", "nonce": "replayed-nonce"})
+	if status != http.StatusUnauthorized || message != "Invalid ID token nonce" {
+		t.Fatalf("nonce mismatch: status %d (%s), want 401 Invalid ID token nonce", status, message)
+	}
+
+	var count int64
+	db.Model(&user.User{}).Count(&count)
+	if count != 0 {
+		t.Errorf("users after rejected login = %d, want 0", count)
+	}
+}
+
+func TestCallbackLinksVerifiedEmail(t *testing.T) {
+	app, db, fake := federationTestApp(t, func(p *Provider) { p.LinkByEmail = true })
+
+	verifiedAt := time.Now()
+	email := "budi@example.com"
+	existing := user.User{Username: "budi", Role: user.USER, IsActive: true, Email: &email, EmailVerifiedAt: &verifiedAt}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	status, message := signIn(t, app, fake, jwt.MapClaims{"sub": "subject-1", "email": "BUDI@example.com", "email_verified": "true"})
+	if status != http.StatusOK {
+		t.Fatalf("callback: status %d (%s), want 200", status, message)
+	}
+
+	var identity user.UserIdentity
+	if err := db.Where("provider = ? AND subject = ?", "test", "subject-1").First(&identity).Error; err != nil || identity.UserId != existing.Id {
+		t.Fatalf("identity = %+v (err %v), want linked to existing user %d", identity, err, existing.Id)
+	}
+}
+
+func TestCallbackDoesNotLinkUnverifiedEmail(t *testing.T) {
+	app, db, fake := federationTestApp(t, func(p *Provider) { p.LinkByEmail = true })
+
+	verifiedEmail, unverifiedEmail := "budi@example.com", "ani@example.com"
+	verifiedAt := time.Now()
+	db.Create(&user.User{Username: "budi", Role: user.USER, IsActive: true, Email: &verifiedEmail, EmailVerifiedAt: &verifiedAt})
+	db.Create(&user.User{Username: "ani", Role: user.USER, IsActive: true, Email: &unverifiedEmail})
+
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"email not verified by provider", jwt.MapClaims{"sub": "subject-1", "email": verifiedEmail, "email_verified": false}},
+		{"email_verified claim missing", jwt.MapClaims{"sub": "subject-2", "email": verifiedEmail}},
+		{"local email not verified", jwt.MapClaims{"sub": "subject-3", "email": unverifiedEmail, "email_verified": true}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, message := signIn(t, app, fake, tc.claims)
+			if status != http.StatusForbidden || message != "No account is linked to this identity" {
+				t.Errorf("status %d (%s), want 403 No account is linked", status, message)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&user.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Errorf("identities = %d, want 0", count)
+	}
+}
@@ -0,0 +1,13 @@
+package federation
+
+import (
+	"os"
+	"testing"
+
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.SetSecrets()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,26 @@
+package oauth
+
+import "github.com/gofiber/fiber/v2"
+
+type ClientController struct {
+	Service ClientService
+}
+
+func NewClientController(service ClientService) *ClientController {
+	return &ClientController{Service: service}
+}
+
+func (cc *ClientController) Create(ctx *fiber.Ctx) error {
+	response := cc.Service.Create(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (cc *ClientController) List(ctx *fiber.Ctx) error {
+	response := cc.Service.List(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+func (cc *ClientController) Delete(ctx *fiber.Ctx) error {
+	response := cc.Service.Delete(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
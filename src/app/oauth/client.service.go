@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// ClientService interface
+type ClientService interface {
+	Create(ctx *fiber.Ctx) utility.APIResponse
+	List(ctx *fiber.Ctx) utility.APIResponse
+	Delete(ctx *fiber.Ctx) utility.APIResponse
+}
+
+// ClientServiceImpl struct
+type ClientServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+// Konstruktor untuk ClientService
+func NewClientService(db *gorm.DB) ClientService {
+	return &ClientServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+	}
+}
+
+// Implementasi Create (mendaftarkan client baru, secret hanya ditampilkan sekali)
+func (c *ClientServiceImpl) Create(ctx *fiber.Ctx) utility.APIResponse {
+	var dto CreateClientDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := c.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	client := Client{
+		ClientId:         uuid.NewString(),
+		Name:             dto.Name,
+		RedirectURIs:     dto.RedirectURIs,
+		Scopes:           dto.Scopes,
+		GrantTypes:       dto.GrantTypes,
+		Public:           dto.Public,
+		Trusted:          dto.Trusted,
+		ServiceAccountId: dto.ServiceAccountId,
+	}
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = defaultGrantTypes
+	}
+
+	if client.allowsGrant("authorization_code") && len(client.RedirectURIs) == 0 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"redirect_uris harus diisi untuk grant authorization_code"})
+	}
+	if client.Public && client.allowsGrant("client_credentials") {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"client public tidak boleh memakai grant client_credentials"})
+	}
+
+	if client.ServiceAccountId != nil {
+		if !client.allowsGrant("client_credentials") {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"service_account_id hanya untuk client dengan grant client_credentials"})
+		}
+		var account user.User
+		if err := c.DB.Select("id", "kind").First(&account, *client.ServiceAccountId).Error; err != nil || !account.IsServiceAccount() {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"service_account_id harus service account yang ada"})
+		}
+	}
+
+	// Redirect URI tidak boleh mengandung fragment (RFC 6749 section 3.1.2)
+	for _, redirectURI := range dto.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || parsed.Fragment != "" || parsed.Scheme == "" {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"redirect_uris tidak valid: " + redirectURI})
+		}
+	}
+
+	var secret string
+	if !client.Public {
+		var err error
+		secret, err = helper.GenerateRandomToken(32)
+		if err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create client", []string{err.Error()})
+		}
+		secretHash := helper.HashToken(secret)
+		client.SecretHash = &secretHash
+	}
+
+	if err := c.DB.Create(&client).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create client", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusCreated, "Client created, store the client secret in a safe place", fiber.Map{
+		"client":        client,
+		"client_secret": secret,
+	})
+}
+
+// Implementasi List (semua client terdaftar)
+func (c *ClientServiceImpl) List(ctx *fiber.Ctx) utility.APIResponse {
+	var clients []Client
+	if err := c.DB.Order("id ASC").Find(&clients).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve clients", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", clients)
+}
+
+// Implementasi Delete (menghapus client beserta kode otorisasi yang belum ditukar)
+func (c *ClientServiceImpl) Delete(ctx *fiber.Ctx) utility.APIResponse {
+	var client Client
+	if err := c.DB.First(&client, ctx.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "Client not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve client", []string{err.Error()})
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", client.ClientId).Delete(&AuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to delete client", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Client deleted successfully", nil)
+}
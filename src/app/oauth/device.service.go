@@ -0,0 +1,279 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+const (
+	// deviceCodeGrantType grant_type untuk polling token perangkat (RFC 8628 section 3.4)
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// devicePollInterval jeda minimal (detik) antar polling token
+	devicePollInterval = 5
+	// userCodeAlphabet huruf konsonan saja agar mudah diketik dan tidak membentuk kata (RFC 8628 section 6.1)
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+)
+
+// deviceCodeTTL masa berlaku device code (dalam menit), default 10 menit
+func deviceCodeTTL() time.Duration {
+	return time.Minute * time.Duration(helper.GetEnvInt("DEVICE_CODE_EXPIRATION", 10))
+}
+
+// Implementasi DeviceAuthorization (RFC 8628 section 3.1): membuat device code & user code untuk perangkat
+func (o *OAuthServiceImpl) DeviceAuthorization(ctx *fiber.Ctx) (fiber.Map, *OAuthError) {
+	client, oauthErr := o.authenticateClient(ctx)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if !client.allowsGrant(deviceCodeGrantType) {
+		return nil, oauthError(http.StatusBadRequest, "unauthorized_client", "The client is not allowed to use the device authorization grant")
+	}
+
+	scope := ctx.FormValue("scope")
+	if !client.allowsScope(scope) {
+		return nil, oauthError(http.StatusBadRequest, "invalid_scope", "The requested scope is not allowed for this client")
+	}
+
+	deviceCode, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to create device code")
+	}
+
+	// User code pendek bisa bentrok dengan kode lama, coba beberapa kali
+	record := DeviceCode{
+		DeviceCodeHash: helper.HashToken(deviceCode),
+		ClientId:       client.ClientId,
+		Scope:          scope,
+		Status:         "pending",
+		Interval:       devicePollInterval,
+		ExpiresAt:      time.Now().Add(deviceCodeTTL()),
+	}
+	for attempt := 0; ; attempt++ {
+		if record.UserCode, err = generateUserCode(); err == nil {
+			if err = o.DB.Create(&record).Error; err == nil {
+				break
+			}
+		}
+		if attempt == 2 {
+			return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to create device code")
+		}
+	}
+
+	verificationURI := issuer() + "/oauth/device"
+	return fiber.Map{
+		"device_code":               deviceCode,
+		"user_code":                 record.UserCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?user_code=" + record.UserCode,
+		"expires_in":                int(deviceCodeTTL().Seconds()),
+		"interval":                  record.Interval,
+	}, nil
+}
+
+// Implementasi Device (halaman verifikasi: user login, memasukkan user code, lalu menyetujui perangkat)
+func (o *OAuthServiceImpl) Device(ctx *fiber.Ctx) AuthorizeResult {
+	page := &AuthorizePage{
+		Flow:      "device",
+		Step:      "login",
+		CSRFToken: csrfToken(ctx),
+		UserCode:  normalizeUserCode(ctx.FormValue("user_code")),
+	}
+	sessionUser, authTime := o.sessionUser(ctx)
+
+	if ctx.Method() == fiber.MethodPost {
+		var result *AuthorizeResult
+		if sessionUser, authTime, result = o.handleLogin(ctx, page, sessionUser, authTime); result != nil {
+			return *result
+		}
+
+		action := ctx.FormValue("action")
+		if sessionUser != nil && (action == "approve" || action == "deny") {
+			return o.decideDevice(page, *sessionUser, authTime, action == "approve")
+		}
+	}
+
+	if sessionUser == nil {
+		return AuthorizeResult{Status: http.StatusOK, Page: page}
+	}
+
+	page.Step = "device_code"
+	if page.UserCode == "" {
+		return AuthorizeResult{Status: http.StatusOK, Page: page}
+	}
+
+	record, client, ok := o.pendingDevice(page.UserCode)
+	if !ok {
+		page.Error = "The code is invalid or has expired"
+		return AuthorizeResult{Status: http.StatusBadRequest, Page: page}
+	}
+
+	// Selalu minta persetujuan eksplisit, termasuk untuk client trusted, karena kode bisa dikirim oleh pihak lain
+	page.Step = "consent"
+	page.ClientName = client.Name
+	page.Scopes = strings.Fields(record.Scope)
+	page.Username = sessionUser.Username
+	return AuthorizeResult{Status: http.StatusOK, Page: page}
+}
+
+// decideDevice menyimpan keputusan user (setuju / tolak) untuk user code yang masih menunggu
+func (o *OAuthServiceImpl) decideDevice(page *AuthorizePage, u user.User, authTime time.Time, approved bool) AuthorizeResult {
+	record, _, ok := o.pendingDevice(page.UserCode)
+	if !ok {
+		page.Step = "device_code"
+		page.Error = "The code is invalid or has expired"
+		return AuthorizeResult{Status: http.StatusBadRequest, Page: page}
+	}
+
+	updates := map[string]interface{}{"status": "denied"}
+	if approved {
+		updates = map[string]interface{}{"status": "approved", "user_id": u.Id, "auth_time": authTime}
+	}
+
+	// Update bersyarat agar kode tidak diputuskan dua kali secara bersamaan
+	result := o.DB.Model(&DeviceCode{}).Where("id = ? AND status = ?", record.Id, "pending").Updates(updates)
+	if result.Error != nil {
+		return errorPage(http.StatusInternalServerError, "Failed to save your decision")
+	}
+	if result.RowsAffected == 0 {
+		page.Step = "device_code"
+		page.Error = "The code is invalid or has expired"
+		return AuthorizeResult{Status: http.StatusBadRequest, Page: page}
+	}
+
+	page.Step = "done"
+	page.Message = "Access denied. You can close this window."
+	if approved {
+		page.Message = "Your device is now connected. You can return to it and close this window."
+	}
+	return AuthorizeResult{Status: http.StatusOK, Page: page}
+}
+
+// exchangeDeviceCode melayani polling token perangkat (RFC 8628 section 3.4 - 3.5)
+func (o *OAuthServiceImpl) exchangeDeviceCode(ctx *fiber.Ctx, client *Client) (*TokenResponse, *OAuthError) {
+	deviceCode := ctx.FormValue("device_code")
+	if deviceCode == "" {
+		return nil, oauthError(http.StatusBadRequest, "invalid_request", "Missing device_code")
+	}
+
+	var record DeviceCode
+	if err := o.DB.Where("device_code_hash = ?", helper.HashToken(deviceCode)).First(&record).Error; err != nil || record.ClientId != client.ClientId {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Invalid device_code")
+	}
+
+	now := time.Now()
+	if now.After(record.ExpiresAt) {
+		return nil, oauthError(http.StatusBadRequest, "expired_token", "The device code has expired")
+	}
+
+	// Polling terlalu cepat: interval dinaikkan 5 detik (RFC 8628 section 3.5)
+	if record.LastPolledAt != nil && now.Sub(*record.LastPolledAt) < time.Duration(record.Interval)*time.Second {
+		o.DB.Model(&DeviceCode{}).Where("id = ?", record.Id).Updates(map[string]interface{}{
+			"interval":       record.Interval + devicePollInterval,
+			"last_polled_at": now,
+		})
+		return nil, oauthError(http.StatusBadRequest, "slow_down", "Polling too frequently, increase the interval")
+	}
+	o.DB.Model(&DeviceCode{}).Where("id = ?", record.Id).Update("last_polled_at", now)
+
+	switch record.Status {
+	case "pending":
+		return nil, oauthError(http.StatusBadRequest, "authorization_pending", "The user has not yet approved the request")
+	case "denied":
+		return nil, oauthError(http.StatusBadRequest, "access_denied", "The user denied the request")
+	case "approved":
+	default:
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "The device code has already been used")
+	}
+
+	// Update bersyarat agar device code hanya bisa ditukar sekali
+	result := o.DB.Model(&DeviceCode{}).Where("id = ? AND status = ?", record.Id, "approved").Update("status", "used")
+	if result.Error != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to redeem device code")
+	}
+	if result.RowsAffected == 0 || record.UserId == nil {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "The device code has already been used")
+	}
+
+	var foundUser user.User
+	if err := o.DB.Where("id = ? AND is_active = ?", *record.UserId, true).First(&foundUser).Error; err != nil {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "User is no longer active")
+	}
+
+	session, err := auth.IssueSessionWithOptions(o.DB, foundUser, auth.SessionOptions{
+		ClientId: client.ClientId,
+		Scope:    record.Scope,
+		FamilyId: uuid.NewString(),
+	})
+	if err != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue tokens")
+	}
+
+	response := tokenResponse(session)
+	if hasScope(record.Scope, "openid") {
+		if response.IdToken, err = issueIDToken(foundUser, client.ClientId, record.Scope, "", record.AuthTime); err != nil {
+			return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue ID token")
+		}
+	}
+	return response, nil
+}
+
+// pendingDevice mencari permintaan perangkat yang masih menunggu persetujuan berdasarkan user code
+func (o *OAuthServiceImpl) pendingDevice(userCode string) (DeviceCode, Client, bool) {
+	var record DeviceCode
+	var client Client
+	if userCode == "" {
+		return record, client, false
+	}
+
+	if err := o.DB.Where("user_code = ? AND status = ? AND expires_at > ?", userCode, "pending", time.Now()).First(&record).Error; err != nil {
+		return record, client, false
+	}
+	if err := o.DB.Where("client_id = ?", record.ClientId).First(&client).Error; err != nil {
+		return record, client, false
+	}
+	return record, client, true
+}
+
+// generateUserCode membuat user code acak dengan format XXXX-XXXX
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+
+	var code strings.Builder
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+// normalizeUserCode menyeragamkan input user code (huruf besar, tanpa spasi / tanda hubung)
+func normalizeUserCode(input string) string {
+	var code strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			code.WriteRune(r)
+		}
+	}
+
+	normalized := code.String()
+	if len(normalized) != 8 {
+		return normalized
+	}
+	return normalized[:4] + "-" + normalized[4:]
+}
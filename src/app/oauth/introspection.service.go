@@ -0,0 +1,199 @@
+package oauth
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// inactiveToken response introspeksi untuk token yang tidak valid (RFC 7662 section 2.2)
+var inactiveToken = fiber.Map{"active": false}
+
+// Implementasi Introspect (RFC 7662): status dan claims access token JWT maupun refresh token opaque.
+// Hanya client confidential yang boleh melakukan introspeksi.
+func (o *OAuthServiceImpl) Introspect(ctx *fiber.Ctx) (fiber.Map, *OAuthError) {
+	client, oauthErr := o.authenticateClient(ctx)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if client.Public {
+		return nil, oauthError(http.StatusUnauthorized, "invalid_client", "Public clients cannot introspect tokens")
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		return nil, oauthError(http.StatusBadRequest, "invalid_request", "Missing token")
+	}
+
+	// token_type_hint hanya menentukan urutan pencarian
+	if ctx.FormValue("token_type_hint") == "refresh_token" {
+		if result := o.introspectRefreshToken(token, client); result != nil {
+			return result, nil
+		}
+		return o.introspectAccessToken(token), nil
+	}
+
+	result := o.introspectAccessToken(token)
+	if result["active"] == true {
+		return result, nil
+	}
+	if result := o.introspectRefreshToken(token, client); result != nil {
+		return result, nil
+	}
+	return inactiveToken, nil
+}
+
+// Implementasi Revoke (RFC 7009): mencabut access token atau refresh token milik client.
+// Token tidak dikenal tetap dianggap berhasil agar tidak membocorkan informasi.
+func (o *OAuthServiceImpl) Revoke(ctx *fiber.Ctx) *OAuthError {
+	client, oauthErr := o.authenticateClient(ctx)
+	if oauthErr != nil {
+		return oauthErr
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		return oauthError(http.StatusBadRequest, "invalid_request", "Missing token")
+	}
+
+	switch ctx.FormValue("token_type_hint") {
+	case "", "access_token", "refresh_token":
+	default:
+		return oauthError(http.StatusBadRequest, "unsupported_token_type", "Unsupported token_type_hint")
+	}
+
+	// Refresh token: seluruh family dicabut, sama seperti logout
+	var refreshToken auth.RefreshToken
+	if err := o.DB.Where("token_hash = ?", helper.HashToken(token)).First(&refreshToken).Error; err == nil {
+		if refreshToken.ClientId != nil && *refreshToken.ClientId == client.ClientId {
+			if err := auth.RevokeRefreshTokenFamily(o.DB, refreshToken.FamilyId); err != nil {
+				return oauthError(http.StatusServiceUnavailable, "temporarily_unavailable", "Failed to revoke token")
+			}
+		}
+		return nil
+	}
+
+	// Access token JWT: hanya token yang diterbitkan untuk client ini
+	claims, ok := parseAccessToken(token)
+	if !ok || claims["client_id"] != client.ClientId {
+		return nil
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(float64)
+	expiresAt, err := claims.GetExpirationTime()
+	if jti == "" || err != nil || expiresAt == nil {
+		return nil
+	}
+	if err := helper.RevokeToken(jti, int64(userID), expiresAt.Time); err != nil {
+		return oauthError(http.StatusServiceUnavailable, "temporarily_unavailable", "Failed to revoke token")
+	}
+	return nil
+}
+
+// introspectAccessToken memeriksa access token JWT (signature, masa berlaku, pencabutan, status user)
+func (o *OAuthServiceImpl) introspectAccessToken(token string) fiber.Map {
+	claims, ok := parseAccessToken(token)
+	if !ok {
+		return inactiveToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(float64)
+	issuedAt, ok := helper.TokenIssuedAt(claims)
+	if !ok || helper.IsTokenRevoked(jti, int64(userID), issuedAt) {
+		return inactiveToken
+	}
+
+	result := fiber.Map{
+		"active":     true,
+		"token_type": "Bearer",
+		"jti":        jti,
+		"iat":        claims["iat"],
+		"exp":        claims["exp"],
+		"iss":        issuer(),
+	}
+	for _, key := range []string{"scope", "client_id", "act"} {
+		if value, ok := claims[key]; ok {
+			result[key] = value
+		}
+	}
+
+	if claims["subject_type"] == "client" {
+		result["sub"] = claims["sub"]
+		result["subject_type"] = "client"
+		return result
+	}
+
+	// User sudah dinonaktifkan: token tidak lagi aktif meskipun belum kedaluwarsa
+	var foundUser user.User
+	if err := o.DB.Where("id = ? AND is_active = ?", int64(userID), true).First(&foundUser).Error; err != nil {
+		return inactiveToken
+	}
+	result["sub"] = strconv.FormatInt(foundUser.Id, 10)
+	result["subject_type"] = "user"
+	result["username"] = foundUser.Username
+	result["role"] = string(foundUser.Role)
+	return result
+}
+
+// introspectRefreshToken memeriksa refresh token opaque milik client ini (nil jika tidak ditemukan)
+func (o *OAuthServiceImpl) introspectRefreshToken(token string, client *Client) fiber.Map {
+	var refreshToken auth.RefreshToken
+	if err := o.DB.Where("token_hash = ?", helper.HashToken(token)).First(&refreshToken).Error; err != nil {
+		return nil
+	}
+
+	// Refresh token hanya boleh diintrospeksi oleh client pemiliknya
+	if refreshToken.ClientId == nil || *refreshToken.ClientId != client.ClientId {
+		return inactiveToken
+	}
+	if refreshToken.RotatedAt != nil || refreshToken.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+		return inactiveToken
+	}
+
+	var foundUser user.User
+	if err := o.DB.Where("id = ? AND is_active = ?", refreshToken.UserId, true).First(&foundUser).Error; err != nil {
+		return inactiveToken
+	}
+
+	result := fiber.Map{
+		"active":       true,
+		"token_type":   "refresh_token",
+		"client_id":    client.ClientId,
+		"sub":          strconv.FormatInt(foundUser.Id, 10),
+		"subject_type": "user",
+		"username":     foundUser.Username,
+		"iat":          refreshToken.CreatedAt.Unix(),
+		"exp":          refreshToken.ExpiresAt.Unix(),
+		"iss":          issuer(),
+	}
+	if refreshToken.Scope != nil {
+		result["scope"] = *refreshToken.Scope
+	}
+	return result
+}
+
+// parseAccessToken memvalidasi JWT dan memastikan token adalah access token (bukan ID / MFA token)
+func parseAccessToken(token string) (jwt.MapClaims, bool) {
+	parsed, err := helper.ValidateJWT(token)
+	if err != nil || !parsed.Valid {
+		return nil, false
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, false
+	}
+	if use, ok := claims["token_use"]; ok && use != "access" {
+		return nil, false
+	}
+	return claims, true
+}
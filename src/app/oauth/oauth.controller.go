@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"embed"
+	"html/template"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// authorizeTemplate halaman login / consent endpoint authorize
+var authorizeTemplate = template.Must(template.ParseFS(templateFS, "templates/authorize.html"))
+
+type OAuthController struct {
+	Service OAuthService
+}
+
+func NewOAuthController(service OAuthService) *OAuthController {
+	return &OAuthController{Service: service}
+}
+
+// Authorize merender halaman login / consent atau redirect ke client
+func (oc *OAuthController) Authorize(ctx *fiber.Ctx) error {
+	return oc.render(ctx, oc.Service.Authorize(ctx))
+}
+
+// Device merender halaman verifikasi perangkat
+func (oc *OAuthController) Device(ctx *fiber.Ctx) error {
+	return oc.render(ctx, oc.Service.Device(ctx))
+}
+
+// DeviceAuthorization mengembalikan device code & user code (RFC 8628)
+func (oc *OAuthController) DeviceAuthorization(ctx *fiber.Ctx) error {
+	response, oauthErr := oc.Service.DeviceAuthorization(ctx)
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if oauthErr != nil {
+		return oc.oauthError(ctx, oauthErr)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// render menulis hasil authorize / device: redirect ke client atau halaman HTML
+func (oc *OAuthController) render(ctx *fiber.Ctx, result AuthorizeResult) error {
+	// Halaman berisi token CSRF / MFA, jangan di-cache
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if result.Redirect != "" {
+		return ctx.Redirect(result.Redirect, result.Status)
+	}
+
+	ctx.Status(result.Status).Type("html", "utf-8")
+	return authorizeTemplate.Execute(ctx.Response().BodyWriter(), result.Page)
+}
+
+// Token mengembalikan response token / error dalam format RFC 6749
+func (oc *OAuthController) Token(ctx *fiber.Ctx) error {
+	response, oauthErr := oc.Service.Token(ctx)
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderPragma, "no-cache")
+	if oauthErr != nil {
+		return oc.oauthError(ctx, oauthErr)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// Introspect mengembalikan status token dalam format RFC 7662
+func (oc *OAuthController) Introspect(ctx *fiber.Ctx) error {
+	response, oauthErr := oc.Service.Introspect(ctx)
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if oauthErr != nil {
+		return oc.oauthError(ctx, oauthErr)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// Revoke mencabut token (RFC 7009), response 200 tanpa body jika berhasil
+func (oc *OAuthController) Revoke(ctx *fiber.Ctx) error {
+	if oauthErr := oc.Service.Revoke(ctx); oauthErr != nil {
+		return oc.oauthError(ctx, oauthErr)
+	}
+	return ctx.SendStatus(fiber.StatusOK)
+}
+
+// oauthError menulis error format RFC 6749, dengan challenge Basic untuk kegagalan autentikasi client
+func (oc *OAuthController) oauthError(ctx *fiber.Ctx, oauthErr *OAuthError) error {
+	if oauthErr.Status == fiber.StatusUnauthorized {
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+	return ctx.Status(oauthErr.Status).JSON(oauthErr)
+}
@@ -0,0 +1,11 @@
+package oauth
+
+type CreateClientDTO struct {
+	Name             string   `json:"name" validate:"required,max=100"`
+	RedirectURIs     []string `json:"redirect_uris" validate:"dive,url"`
+	Scopes           []string `json:"scopes" validate:"dive,required,max=100,excludesall= "`
+	GrantTypes       []string `json:"grant_types" validate:"dive,oneof=authorization_code refresh_token client_credentials urn:ietf:params:oauth:grant-type:device_code"`
+	Public           bool     `json:"public"`
+	Trusted          bool     `json:"trusted"`
+	ServiceAccountId *int64   `json:"service_account_id"`
+}
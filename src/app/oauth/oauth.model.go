@@ -0,0 +1,127 @@
+package oauth
+
+import "time"
+
+// Client adalah aplikasi yang terdaftar untuk login lewat OAuth2.
+// Client public (SPA / mobile) tidak punya secret dan wajib memakai PKCE.
+type Client struct {
+	Id               int64     `gorm:"primaryKey" json:"id"`
+	ClientId         string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"client_id"`
+	SecretHash       *string   `gorm:"type:char(64);null" json:"-"`
+	Name             string    `gorm:"type:varchar(100);not null" json:"name"`
+	RedirectURIs     []string  `gorm:"type:text;serializer:json" json:"redirect_uris"`
+	Scopes           []string  `gorm:"type:text;serializer:json" json:"scopes"`      // Kosong berarti scope bebas
+	GrantTypes       []string  `gorm:"type:text;serializer:json" json:"grant_types"` // Kosong berarti authorization_code & refresh_token
+	Public           bool      `gorm:"default:false" json:"public"`
+	Trusted          bool      `gorm:"default:false" json:"trusted"`         // Aplikasi first-party, halaman consent dilewati
+	ServiceAccountId *int64    `gorm:"index;null" json:"service_account_id"` // Token client_credentials diterbitkan atas nama service account ini
+	CreatedAt        time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (Client) TableName() string {
+	return "oauth_clients"
+}
+
+// AuthorizationCode adalah kode sekali pakai dari endpoint authorize, disimpan dalam bentuk hash
+type AuthorizationCode struct {
+	Id                  int64      `gorm:"primaryKey" json:"id"`
+	CodeHash            string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ClientId            string     `gorm:"type:varchar(64);not null;index" json:"client_id"`
+	UserId              int64      `gorm:"not null;index" json:"user_id"`
+	RedirectURI         string     `gorm:"type:text;not null" json:"redirect_uri"`
+	RedirectURIOmitted  bool       `gorm:"not null;default:false" json:"-"` // redirect_uri tidak wajib saat penukaran kode (RFC 6749 section 4.1.3)
+	Scope               string     `gorm:"type:varchar(255)" json:"scope"`
+	CodeChallenge       *string    `gorm:"type:varchar(128);null" json:"-"`
+	CodeChallengeMethod *string    `gorm:"type:varchar(10);null" json:"-"`
+	Nonce               *string    `gorm:"type:varchar(255);null" json:"-"`
+	AuthTime            *time.Time `gorm:"type:datetime;null" json:"-"`
+	FamilyId            *string    `gorm:"type:varchar(36);null" json:"-"` // Family refresh token hasil penukaran kode
+	ExpiresAt           time.Time  `gorm:"type:datetime;not null" json:"expires_at"`
+	UsedAt              *time.Time `gorm:"type:datetime;null" json:"used_at"`
+	CreatedAt           time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (AuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// DeviceCode permintaan otorisasi perangkat (RFC 8628); device code disimpan dalam bentuk hash
+type DeviceCode struct {
+	Id             int64      `gorm:"primaryKey" json:"id"`
+	DeviceCodeHash string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	UserCode       string     `gorm:"type:varchar(9);not null;uniqueIndex" json:"user_code"`
+	ClientId       string     `gorm:"type:varchar(64);not null;index" json:"client_id"`
+	Scope          string     `gorm:"type:varchar(255)" json:"scope"`
+	Status         string     `gorm:"type:varchar(10);not null;default:'pending'" json:"status"` // pending | approved | denied | used
+	UserId         *int64     `gorm:"null" json:"user_id"`
+	AuthTime       *time.Time `gorm:"type:datetime;null" json:"-"`
+	Interval       int        `gorm:"not null" json:"interval"`
+	LastPolledAt   *time.Time `gorm:"type:datetime;null" json:"-"`
+	ExpiresAt      time.Time  `gorm:"type:datetime;not null" json:"expires_at"`
+	CreatedAt      time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (DeviceCode) TableName() string {
+	return "oauth_device_codes"
+}
+
+// TokenResponse format response sukses endpoint token (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IdToken      string `json:"id_token,omitempty"`
+}
+
+// OAuthError format error endpoint token (RFC 6749 section 5.2)
+type OAuthError struct {
+	Status           int    `json:"-"`
+	Code             string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.ErrorDescription
+}
+
+// AuthorizeRequest parameter permintaan otorisasi, dibawa ulang sebagai hidden field di form login/consent
+type AuthorizeRequest struct {
+	ClientId            string
+	RedirectURI         string
+	RedirectURIOmitted  bool // redirect_uri tidak dikirim client, memakai satu-satunya URI terdaftar
+	ResponseType        string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// AuthorizePage data yang dirender ke halaman login / consent
+type AuthorizePage struct {
+	Flow       string // authorize | device
+	Step       string // login | mfa | passkey | consent | device_code | done | error
+	Error      string
+	Message    string
+	ClientName string
+	Scopes     []string
+	Username   string
+	MFAToken   string
+	CSRFToken  string
+	UserCode   string
+	Request    AuthorizeRequest
+
+	// Opsi WebAuthn (JSON) dan session ceremony untuk step passkey
+	PasskeyOptions string
+	PasskeySession string
+}
+
+// AuthorizeResult hasil endpoint authorize: redirect ke client atau halaman yang harus dirender
+type AuthorizeResult struct {
+	Status   int
+	Redirect string
+	Page     *AuthorizePage
+}
@@ -0,0 +1,39 @@
+package oauth
+
+import (
+	"github.com/achyar10/go-auth/src/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SetupRoutes mengatur routing OAuth2 authorization server
+func SetupRoutes(app *fiber.App, db *gorm.DB) {
+	oauthService := NewOAuthService(db)
+	oauthController := NewOAuthController(oauthService)
+	clientService := NewClientService(db)
+	clientController := NewClientController(clientService)
+	oidcService := NewOIDCService(db)
+	oidcController := NewOIDCController(oidcService)
+
+	app.Get("/.well-known/openid-configuration", oidcController.Discovery)
+
+	oauthRoutes := app.Group("/oauth")
+	oauthRoutes.Get("/authorize", oauthController.Authorize)
+	// Form login (password & kode MFA) dikirim ke POST /authorize
+	oauthRoutes.Post("/authorize", middleware.RateLimit("oauth_authorize_ip", "30/1m", middleware.RateLimitByIP), oauthController.Authorize)
+	oauthRoutes.Post("/device_authorization", oauthController.DeviceAuthorization)
+	oauthRoutes.Get("/device", oauthController.Device)
+	oauthRoutes.Post("/device", oauthController.Device)
+	// Rate limit per client (dari header Basic atau client_id di body)
+	oauthRoutes.Post("/token", middleware.RateLimit("oauth_token_client", "60/1m", middleware.RateLimitByClient), oauthController.Token)
+	oauthRoutes.Post("/introspect", middleware.RateLimit("oauth_introspect_client", "600/1m", middleware.RateLimitByClient), oauthController.Introspect)
+	oauthRoutes.Post("/revoke", oauthController.Revoke)
+	oauthRoutes.Get("/userinfo", middleware.OAuthMiddleware, middleware.RequireUser, middleware.RequireScope("openid"), oidcController.UserInfo)
+	oauthRoutes.Post("/userinfo", middleware.OAuthMiddleware, middleware.RequireUser, middleware.RequireScope("openid"), oidcController.UserInfo)
+
+	// Manajemen client (admin)
+	clientRoutes := oauthRoutes.Group("/clients", middleware.AuthMiddleware, middleware.RoleMiddleware("admin"))
+	clientRoutes.Post("/", clientController.Create)
+	clientRoutes.Get("/", clientController.List)
+	clientRoutes.Delete("/:id", clientController.Delete)
+}
@@ -0,0 +1,705 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/passkey"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+const (
+	// authorizationCodeTTL masa berlaku kode otorisasi
+	authorizationCodeTTL = 5 * time.Minute
+	// mfaChallengeTTL masa berlaku token tantangan MFA di halaman login
+	mfaChallengeTTL = 5 * time.Minute
+
+	sessionCookie = "oauth_session"
+	csrfCookie    = "oauth_csrf"
+)
+
+// defaultGrantTypes grant yang diizinkan untuk client tanpa daftar grant_types
+var defaultGrantTypes = []string{"authorization_code", "refresh_token"}
+
+// codeVerifierPattern format code_verifier sesuai RFC 7636 section 4.1
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// OAuthService interface
+type OAuthService interface {
+	Authorize(ctx *fiber.Ctx) AuthorizeResult
+	Token(ctx *fiber.Ctx) (*TokenResponse, *OAuthError)
+	Introspect(ctx *fiber.Ctx) (fiber.Map, *OAuthError)
+	Revoke(ctx *fiber.Ctx) *OAuthError
+	DeviceAuthorization(ctx *fiber.Ctx) (fiber.Map, *OAuthError)
+	Device(ctx *fiber.Ctx) AuthorizeResult
+}
+
+// OAuthServiceImpl struct
+type OAuthServiceImpl struct {
+	DB       *gorm.DB
+	Passkeys passkey.PasskeyService // Passkey sebagai faktor kedua di halaman login
+}
+
+// Konstruktor untuk OAuthService
+func NewOAuthService(db *gorm.DB) OAuthService {
+	return &OAuthServiceImpl{DB: db, Passkeys: passkey.NewPasskeyService(db)}
+}
+
+// Implementasi Authorize (halaman login & consent, lalu redirect ke client dengan kode otorisasi)
+func (o *OAuthServiceImpl) Authorize(ctx *fiber.Ctx) AuthorizeResult {
+	request := AuthorizeRequest{
+		ClientId:            ctx.FormValue("client_id"),
+		RedirectURI:         ctx.FormValue("redirect_uri"),
+		ResponseType:        ctx.FormValue("response_type"),
+		Scope:               ctx.FormValue("scope"),
+		State:               ctx.FormValue("state"),
+		CodeChallenge:       ctx.FormValue("code_challenge"),
+		CodeChallengeMethod: ctx.FormValue("code_challenge_method"),
+		Nonce:               ctx.FormValue("nonce"),
+	}
+
+	// Client & redirect_uri harus valid sebelum error boleh dikirim lewat redirect
+	var client Client
+	if request.ClientId == "" || o.DB.Where("client_id = ?", request.ClientId).First(&client).Error != nil {
+		return errorPage(http.StatusBadRequest, "Unknown client")
+	}
+	if request.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		request.RedirectURI = client.RedirectURIs[0]
+		request.RedirectURIOmitted = true
+	}
+	if !client.allowsRedirect(request.RedirectURI) {
+		return errorPage(http.StatusBadRequest, "The redirect_uri is not registered for this client")
+	}
+
+	if request.ResponseType != "code" {
+		return errorRedirect(request, "unsupported_response_type", "Only response_type=code is supported")
+	}
+	if !client.allowsGrant("authorization_code") {
+		return errorRedirect(request, "unauthorized_client", "The client is not allowed to use the authorization code grant")
+	}
+	if !client.allowsScope(request.Scope) {
+		return errorRedirect(request, "invalid_scope", "The requested scope is not allowed for this client")
+	}
+	// Hanya S256; metode plain tidak melindungi kode yang bocor sehingga tidak diterima
+	if request.CodeChallenge != "" && request.CodeChallengeMethod != "S256" {
+		return errorRedirect(request, "invalid_request", "code_challenge_method must be S256")
+	}
+	if client.Public && request.CodeChallenge == "" {
+		return errorRedirect(request, "invalid_request", "PKCE code_challenge is required for public clients")
+	}
+
+	page := &AuthorizePage{
+		Flow:       "authorize",
+		Step:       "login",
+		ClientName: client.Name,
+		Scopes:     strings.Fields(request.Scope),
+		CSRFToken:  csrfToken(ctx),
+		Request:    request,
+	}
+	sessionUser, authTime := o.sessionUser(ctx)
+
+	if ctx.Method() == fiber.MethodPost {
+		var result *AuthorizeResult
+		if sessionUser, authTime, result = o.handleLogin(ctx, page, sessionUser, authTime); result != nil {
+			return *result
+		}
+
+		switch ctx.FormValue("action") {
+		case "deny":
+			if sessionUser != nil {
+				return errorRedirect(request, "access_denied", "The user denied the request")
+			}
+
+		case "approve":
+			if sessionUser != nil {
+				return o.issueCode(request, *sessionUser, authTime)
+			}
+		}
+	}
+
+	if sessionUser == nil {
+		return AuthorizeResult{Status: http.StatusOK, Page: page}
+	}
+
+	// Aplikasi first-party tidak perlu persetujuan user
+	if client.Trusted {
+		return o.issueCode(request, *sessionUser, authTime)
+	}
+
+	page.Step = "consent"
+	page.Username = sessionUser.Username
+	return AuthorizeResult{Status: http.StatusOK, Page: page}
+}
+
+// Implementasi Token (menukar kode otorisasi atau refresh token dengan access token)
+func (o *OAuthServiceImpl) Token(ctx *fiber.Ctx) (*TokenResponse, *OAuthError) {
+	client, oauthErr := o.authenticateClient(ctx)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	grantType := ctx.FormValue("grant_type")
+	if grantType == "" {
+		return nil, oauthError(http.StatusBadRequest, "invalid_request", "Missing grant_type")
+	}
+
+	switch grantType {
+	case "authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType:
+		if !client.allowsGrant(grantType) {
+			return nil, oauthError(http.StatusBadRequest, "unauthorized_client", "The client is not allowed to use this grant type")
+		}
+	default:
+		return nil, oauthError(http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+	}
+
+	switch grantType {
+	case "authorization_code":
+		return o.exchangeCode(ctx, client)
+	case "refresh_token":
+		return o.refresh(ctx, client)
+	case deviceCodeGrantType:
+		return o.exchangeDeviceCode(ctx, client)
+	default:
+		return o.clientCredentials(ctx, client)
+	}
+}
+
+// clientCredentials menerbitkan access token atas nama client sendiri (RFC 6749 section 4.4), tanpa refresh token
+func (o *OAuthServiceImpl) clientCredentials(ctx *fiber.Ctx, client *Client) (*TokenResponse, *OAuthError) {
+	if client.Public {
+		return nil, oauthError(http.StatusBadRequest, "unauthorized_client", "Public clients cannot use the client credentials grant")
+	}
+
+	// Tanpa parameter scope, token mendapat semua scope yang diizinkan untuk client
+	scope := ctx.FormValue("scope")
+	if scope == "" {
+		scope = strings.Join(client.Scopes, " ")
+	}
+	if !client.allowsScope(scope) {
+		return nil, oauthError(http.StatusBadRequest, "invalid_scope", "The requested scope is not allowed for this client")
+	}
+
+	// Client yang terikat ke service account mendapat token user atas nama service account tersebut
+	if client.ServiceAccountId != nil {
+		return o.serviceAccountToken(client, scope)
+	}
+
+	token, err := helper.GenerateClientJWT(client.ClientId, scope, helper.ClientTokenTTL())
+	if err != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue token")
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(helper.ClientTokenTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// exchangeCode menukar kode otorisasi (sekali pakai) dengan token, termasuk verifikasi PKCE
+func (o *OAuthServiceImpl) exchangeCode(ctx *fiber.Ctx, client *Client) (*TokenResponse, *OAuthError) {
+	rawCode := ctx.FormValue("code")
+	if rawCode == "" {
+		return nil, oauthError(http.StatusBadRequest, "invalid_request", "Missing code")
+	}
+
+	var code AuthorizationCode
+	if err := o.DB.Where("code_hash = ?", helper.HashToken(rawCode)).First(&code).Error; err != nil {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Invalid authorization code")
+	}
+
+	// Kode dipakai ulang: cabut token yang sudah diterbitkan dari kode ini (RFC 6749 section 4.1.2)
+	if code.UsedAt != nil {
+		if code.FamilyId != nil {
+			_ = auth.RevokeRefreshTokenFamily(o.DB, *code.FamilyId)
+		}
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Authorization code has already been used")
+	}
+	if code.ClientId != client.ClientId || time.Now().After(code.ExpiresAt) {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Invalid authorization code")
+	}
+	// redirect_uri wajib sama jika dikirim saat authorize (atau dikirim sekarang)
+	if redirectURI := ctx.FormValue("redirect_uri"); (redirectURI != "" || !code.RedirectURIOmitted) && redirectURI != code.RedirectURI {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+	}
+	if code.CodeChallenge != nil && !verifyPKCE(ctx.FormValue("code_verifier"), *code.CodeChallenge, *code.CodeChallengeMethod) {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Invalid code_verifier")
+	}
+
+	// Update bersyarat agar kode tidak bisa ditukar dua kali secara bersamaan
+	familyID := uuid.NewString()
+	result := o.DB.Model(&AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", code.Id).
+		Updates(map[string]interface{}{"used_at": time.Now(), "family_id": familyID})
+	if result.Error != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to redeem authorization code")
+	}
+	if result.RowsAffected == 0 {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "Authorization code has already been used")
+	}
+
+	var foundUser user.User
+	if err := o.DB.Where("id = ? AND is_active = ?", code.UserId, true).First(&foundUser).Error; err != nil {
+		return nil, oauthError(http.StatusBadRequest, "invalid_grant", "User is no longer active")
+	}
+
+	session, err := auth.IssueSessionWithOptions(o.DB, foundUser, auth.SessionOptions{
+		ClientId: client.ClientId,
+		Scope:    code.Scope,
+		FamilyId: familyID,
+	})
+	if err != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue tokens")
+	}
+
+	response := tokenResponse(session)
+	if hasScope(code.Scope, "openid") {
+		nonce := ""
+		if code.Nonce != nil {
+			nonce = *code.Nonce
+		}
+		if response.IdToken, err = issueIDToken(foundUser, client.ClientId, code.Scope, nonce, code.AuthTime); err != nil {
+			return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue ID token")
+		}
+	}
+	return response, nil
+}
+
+// refresh merotasi refresh token milik client ini
+func (o *OAuthServiceImpl) refresh(ctx *fiber.Ctx, client *Client) (*TokenResponse, *OAuthError) {
+	rawToken := ctx.FormValue("refresh_token")
+	if rawToken == "" {
+		return nil, oauthError(http.StatusBadRequest, "invalid_request", "Missing refresh_token")
+	}
+
+	session, err := auth.RotateRefreshToken(o.DB, rawToken, client.ClientId)
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenInvalid) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			return nil, oauthError(http.StatusBadRequest, "invalid_grant", err.Error())
+		}
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to refresh token")
+	}
+
+	response := tokenResponse(session)
+	if hasScope(session.Scope, "openid") {
+		var foundUser user.User
+		if err := o.DB.First(&foundUser, session.Id).Error; err != nil {
+			return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue ID token")
+		}
+		if response.IdToken, err = issueIDToken(foundUser, client.ClientId, session.Scope, "", nil); err != nil {
+			return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue ID token")
+		}
+	}
+	return response, nil
+}
+
+// authenticateClient mengautentikasi client lewat HTTP Basic (client_secret_basic) atau body (client_secret_post).
+// Client public cukup mengirim client_id.
+func (o *OAuthServiceImpl) authenticateClient(ctx *fiber.Ctx) (*Client, *OAuthError) {
+	clientID, clientSecret, usedBasic := clientCredentials(ctx)
+	if clientID == "" {
+		return nil, oauthError(http.StatusUnauthorized, "invalid_client", "Missing client credentials")
+	}
+
+	var client Client
+	if err := o.DB.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, oauthError(http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+	}
+
+	if client.Public {
+		if clientSecret != "" || usedBasic {
+			return nil, oauthError(http.StatusUnauthorized, "invalid_client", "Public clients must not use a client secret")
+		}
+		return &client, nil
+	}
+
+	if client.SecretHash == nil || clientSecret == "" ||
+		subtle.ConstantTimeCompare([]byte(helper.HashToken(clientSecret)), []byte(*client.SecretHash)) != 1 {
+		return nil, oauthError(http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+	}
+	return &client, nil
+}
+
+// handleLogin memeriksa token CSRF lalu memproses aksi login, mfa, passkey dan switch dari form halaman
+// authorize / device. Result tidak nil berarti halaman harus dirender ulang; selain itu mengembalikan
+// user sesi terbaru (nil jika belum login).
+func (o *OAuthServiceImpl) handleLogin(ctx *fiber.Ctx, page *AuthorizePage, sessionUser *user.User, authTime time.Time) (*user.User, time.Time, *AuthorizeResult) {
+	// Double-submit cookie: token di form harus sama dengan cookie
+	submitted := ctx.FormValue("csrf_token")
+	if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(ctx.Cookies(csrfCookie))) != 1 {
+		page.Error = "Your session expired, please try again"
+		return nil, time.Time{}, &AuthorizeResult{Status: http.StatusForbidden, Page: page}
+	}
+
+	switch ctx.FormValue("action") {
+	case "login":
+		foundUser, err := auth.VerifyCredentials(o.DB, ctx.FormValue("username"), ctx.FormValue("password"), ctx.IP())
+		if err != nil {
+			status := http.StatusUnauthorized
+			page.Error = "Invalid username or password"
+			var throttled *auth.LoginThrottledError
+			if errors.As(err, &throttled) {
+				status = http.StatusTooManyRequests
+				ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(throttled.RetryAfterSeconds()))
+				page.Error = fmt.Sprintf("Too many failed attempts, please try again in %d seconds", throttled.RetryAfterSeconds())
+			}
+			if err == auth.ErrEmailNotVerified {
+				page.Error = "Your email address has not been verified"
+			}
+			return nil, time.Time{}, &AuthorizeResult{Status: status, Page: page}
+		}
+
+		methods, err := auth.MFAMethods(o.DB, foundUser)
+		if err != nil {
+			result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+			return nil, time.Time{}, &result
+		}
+		if len(methods) > 0 {
+			mfaToken, err := helper.GeneratePurposeToken("mfa", foundUser.Id, mfaChallengeTTL)
+			if err != nil {
+				result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+				return nil, time.Time{}, &result
+			}
+			page.MFAToken = mfaToken
+
+			// Kode TOTP / pemulihan jika tersedia, selain itu passkey
+			if contains(methods, "totp") {
+				page.Step = "mfa"
+				return nil, time.Time{}, &AuthorizeResult{Status: http.StatusOK, Page: page}
+			}
+			if result := o.passkeyStep(page); result != nil {
+				return nil, time.Time{}, result
+			}
+			return nil, time.Time{}, &AuthorizeResult{Status: http.StatusOK, Page: page}
+		}
+
+		if err := startSession(ctx, foundUser); err != nil {
+			result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+			return nil, time.Time{}, &result
+		}
+		return &foundUser, time.Now(), nil
+
+	case "mfa":
+		foundUser, claims, err := auth.ParseMFAChallenge(o.DB, ctx.FormValue("mfa_token"))
+		if err != nil {
+			page.Error = "Verification expired, please sign in again"
+			return nil, time.Time{}, &AuthorizeResult{Status: http.StatusUnauthorized, Page: page}
+		}
+		if !auth.VerifyMFACode(o.DB, foundUser, ctx.FormValue("code")) {
+			auth.RecordMFAFailure(claims, foundUser.Id)
+			page.Step = "mfa"
+			page.MFAToken = ctx.FormValue("mfa_token")
+			page.Error = "Invalid code"
+			return nil, time.Time{}, &AuthorizeResult{Status: http.StatusUnauthorized, Page: page}
+		}
+		auth.ConsumeMFAChallenge(claims, foundUser.Id)
+
+		if err := startSession(ctx, foundUser); err != nil {
+			result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+			return nil, time.Time{}, &result
+		}
+		return &foundUser, time.Now(), nil
+
+	case "passkey":
+		foundUser, err := o.Passkeys.FinishMFA(ctx.FormValue("passkey_session"), []byte(ctx.FormValue("passkey_credential")))
+		if err != nil {
+			page.Error = "Passkey verification failed, please sign in again"
+			return nil, time.Time{}, &AuthorizeResult{Status: http.StatusUnauthorized, Page: page}
+		}
+
+		if err := startSession(ctx, foundUser); err != nil {
+			result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+			return nil, time.Time{}, &result
+		}
+		return &foundUser, time.Now(), nil
+
+	case "switch":
+		clearSession(ctx)
+		return nil, time.Time{}, &AuthorizeResult{Status: http.StatusOK, Page: page}
+	}
+
+	return sessionUser, authTime, nil
+}
+
+// passkeyStep memulai ceremony passkey untuk page.MFAToken lalu mengisi opsi WebAuthn yang dipakai script
+// di halaman. Result tidak nil berarti ceremony gagal dimulai.
+func (o *OAuthServiceImpl) passkeyStep(page *AuthorizePage) *AuthorizeResult {
+	options, sessionID, err := o.Passkeys.BeginMFA(page.MFAToken)
+	if err != nil {
+		result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+		return &result
+	}
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		result := errorPage(http.StatusInternalServerError, "Failed to sign in")
+		return &result
+	}
+
+	page.Step = "passkey"
+	page.PasskeyOptions = string(encoded)
+	page.PasskeySession = sessionID
+	return nil
+}
+
+// issueCode membuat kode otorisasi lalu mengarahkan user kembali ke client
+func (o *OAuthServiceImpl) issueCode(request AuthorizeRequest, u user.User, authTime time.Time) AuthorizeResult {
+	rawCode, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return errorRedirect(request, "server_error", "Failed to create authorization code")
+	}
+
+	code := AuthorizationCode{
+		CodeHash:           helper.HashToken(rawCode),
+		ClientId:           request.ClientId,
+		UserId:             u.Id,
+		RedirectURI:        request.RedirectURI,
+		RedirectURIOmitted: request.RedirectURIOmitted,
+		Scope:              request.Scope,
+		AuthTime:           &authTime,
+		ExpiresAt:          time.Now().Add(authorizationCodeTTL),
+	}
+	if request.Nonce != "" {
+		code.Nonce = &request.Nonce
+	}
+	if request.CodeChallenge != "" {
+		code.CodeChallenge = &request.CodeChallenge
+		code.CodeChallengeMethod = &request.CodeChallengeMethod
+	}
+	if err := o.DB.Create(&code).Error; err != nil {
+		return errorRedirect(request, "server_error", "Failed to create authorization code")
+	}
+
+	return AuthorizeResult{
+		Status:   http.StatusFound,
+		Redirect: redirectWith(request.RedirectURI, map[string]string{"code": rawCode, "state": request.State}),
+	}
+}
+
+// sessionUser mengambil user dan waktu login dari cookie sesi halaman authorize
+// (nil jika belum login atau sesi dicabut)
+func (o *OAuthServiceImpl) sessionUser(ctx *fiber.Ctx) (*user.User, time.Time) {
+	cookie := ctx.Cookies(sessionCookie)
+	if cookie == "" {
+		return nil, time.Time{}
+	}
+
+	claims, err := helper.ValidatePurposeToken(cookie, sessionCookie)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(float64)
+	issuedAt, ok := helper.TokenIssuedAt(claims)
+	if !ok || helper.IsTokenRevoked(jti, int64(userID), issuedAt) {
+		return nil, time.Time{}
+	}
+
+	var foundUser user.User
+	if err := o.DB.Where("id = ? AND is_active = ?", int64(userID), true).First(&foundUser).Error; err != nil {
+		return nil, time.Time{}
+	}
+	return &foundUser, issuedAt
+}
+
+// startSession menyimpan sesi login halaman authorize di cookie HttpOnly
+func startSession(ctx *fiber.Ctx, u user.User) error {
+	token, err := helper.GeneratePurposeToken(sessionCookie, u.Id, helper.OAuthSessionTTL())
+	if err != nil {
+		return err
+	}
+	ctx.Cookie(&fiber.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/oauth",
+		Expires:  time.Now().Add(helper.OAuthSessionTTL()),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return nil
+}
+
+// clearSession menghapus cookie sesi halaman authorize
+func clearSession(ctx *fiber.Ctx) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/oauth",
+		Expires:  time.Unix(0, 0),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// csrfToken mengembalikan token CSRF dari cookie, atau membuat yang baru
+func csrfToken(ctx *fiber.Ctx) string {
+	if token := ctx.Cookies(csrfCookie); token != "" {
+		return token
+	}
+
+	token, _ := helper.GenerateRandomToken(32)
+	ctx.Cookie(&fiber.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/oauth",
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+	return token
+}
+
+// serviceAccountToken menerbitkan access token (tanpa refresh token) untuk service account milik client
+func (o *OAuthServiceImpl) serviceAccountToken(client *Client, scope string) (*TokenResponse, *OAuthError) {
+	var account user.User
+	if err := o.DB.Where("id = ? AND kind = ? AND is_active = ?", *client.ServiceAccountId, user.KindService, true).First(&account).Error; err != nil {
+		return nil, oauthError(http.StatusBadRequest, "unauthorized_client", "The service account for this client is not active")
+	}
+
+	fullname := ""
+	if account.Fullname != nil {
+		fullname = *account.Fullname
+	}
+	token, err := helper.GenerateScopedJWT(account.Id, account.Username, fullname, string(account.Role), client.ClientId, scope)
+	if err != nil {
+		return nil, oauthError(http.StatusInternalServerError, "server_error", "Failed to issue token")
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(helper.AccessTokenTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// clientCredentials membaca kredensial client dari header Basic atau body form
+func clientCredentials(ctx *fiber.Ctx) (string, string, bool) {
+	header := ctx.Get(fiber.HeaderAuthorization)
+	if strings.HasPrefix(header, "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err != nil {
+			return "", "", true
+		}
+		id, secret, _ := strings.Cut(string(decoded), ":")
+		// Nilai di header Basic di-encode dengan application/x-www-form-urlencoded (RFC 6749 section 2.3.1)
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		return id, secret, true
+	}
+	return ctx.FormValue("client_id"), ctx.FormValue("client_secret"), false
+}
+
+// verifyPKCE mencocokkan code_verifier dengan code_challenge (RFC 7636 section 4.6), hanya metode S256
+func verifyPKCE(verifier string, challenge string, method string) bool {
+	if method != "S256" || !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// allowsGrant mengecek apakah client boleh memakai grant tertentu
+func (c Client) allowsGrant(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return contains(defaultGrantTypes, grantType)
+	}
+	return contains(c.GrantTypes, grantType)
+}
+
+// allowsScope semua scope yang diminta harus ada di daftar scope client (jika daftar diisi)
+func (c Client) allowsScope(scope string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range strings.Fields(scope) {
+		if !contains(c.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsRedirect redirect_uri harus sama persis dengan salah satu URI yang terdaftar
+func (c Client) allowsRedirect(redirectURI string) bool {
+	return redirectURI != "" && contains(c.RedirectURIs, redirectURI)
+}
+
+// tokenResponse mengubah sesi auth menjadi response token OAuth
+func tokenResponse(session auth.LoginResponse) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  session.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(helper.AccessTokenTTL().Seconds()),
+		RefreshToken: session.RefreshToken,
+		Scope:        session.Scope,
+	}
+}
+
+// redirectWith menambahkan parameter query ke redirect_uri (parameter kosong dilewati)
+func redirectWith(redirectURI string, params map[string]string) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := target.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// errorRedirect mengembalikan error ke client lewat redirect_uri (RFC 6749 section 4.1.2.1)
+func errorRedirect(request AuthorizeRequest, code string, description string) AuthorizeResult {
+	return AuthorizeResult{
+		Status: http.StatusFound,
+		Redirect: redirectWith(request.RedirectURI, map[string]string{
+			"error":             code,
+			"error_description": description,
+			"state":             request.State,
+		}),
+	}
+}
+
+// errorPage menampilkan error langsung ke user (client atau redirect_uri tidak bisa dipercaya)
+func errorPage(status int, message string) AuthorizeResult {
+	return AuthorizeResult{Status: status, Page: &AuthorizePage{Step: "error", Error: message}}
+}
+
+func oauthError(status int, code string, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, ErrorDescription: description}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
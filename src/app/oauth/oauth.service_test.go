@@ -0,0 +1,340 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+const (
+	testClientID    = "spa"
+	testRedirectURI = "https://app.example.com/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r7wW1gFWFOEjXk"
+)
+
+// pkceChallenge code_challenge S256 untuk verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// createPublicClient membuat client publik first-party (consent dilewati) beserta user yang sudah login
+func createPublicClient(t *testing.T, db *gorm.DB) (Client, user.User) {
+	t.Helper()
+	client := Client{ClientId: testClientID, Name: "SPA", RedirectURIs: []string{testRedirectURI}, Public: true, Trusted: true}
+	if err := db.Create(&client).Error; err != nil {
+		t.Fatal(err)
+	}
+	account := user.User{Username: "budi", Role: user.USER, IsActive: true}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	return client, account
+}
+
+// newOAuthTestApp app dengan endpoint authorize (hasil dikirim sebagai JSON, tanpa template) dan token
+func newOAuthTestApp(db *gorm.DB) *fiber.App {
+	service := NewOAuthService(db)
+	app := fiber.New()
+	app.Get("/oauth/authorize", func(ctx *fiber.Ctx) error {
+		return ctx.JSON(service.Authorize(ctx))
+	})
+	app.Post("/oauth/token", NewOAuthController(service).Token)
+	return app
+}
+
+// authorize memanggil GET /oauth/authorize dengan cookie sesi milik account
+func authorize(t *testing.T, app *fiber.App, account user.User, params url.Values) AuthorizeResult {
+	t.Helper()
+	session, err := helper.GeneratePurposeToken(sessionCookie, account.Id, helper.OAuthSessionTTL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: session})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result AuthorizeResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// authorizeParams parameter authorize dasar dengan code_challenge dan metode tertentu
+func authorizeParams(challenge string, method string) url.Values {
+	params := url.Values{
+		"client_id":     {testClientID},
+		"redirect_uri":  {testRedirectURI},
+		"response_type": {"code"},
+		"scope":         {"profile"},
+		"state":         {"xyz"},
+	}
+	if challenge != "" {
+		params.Set("code_challenge", challenge)
+	}
+	if method != "" {
+		params.Set("code_challenge_method", method)
+	}
+	return params
+}
+
+// redirectQuery parameter query dari redirect hasil authorize
+func redirectQuery(t *testing.T, result AuthorizeResult) url.Values {
+	t.Helper()
+	if result.Status != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d (page %+v)", result.Status, http.StatusFound, result.Page)
+	}
+	target, err := url.Parse(result.Redirect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return target.Query()
+}
+
+// exchangeCode memanggil POST /oauth/token grant authorization_code
+func exchangeCode(t *testing.T, app *fiber.App, code string, verifier string) (int, map[string]interface{}) {
+	t.Helper()
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"client_id":    {testClientID},
+		"redirect_uri": {testRedirectURI},
+		"code":         {code},
+	}
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+	return postToken(t, app, form)
+}
+
+// postToken memanggil POST /oauth/token dengan form apa adanya
+func postToken(t *testing.T, app *fiber.App, form url.Values) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	challenge := pkceChallenge(testVerifier)
+
+	cases := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+		want      bool
+	}{
+		{"S256 match", testVerifier, challenge, "S256", true},
+		{"S256 wrong verifier", strings.Replace(testVerifier, "d", "e", 1), challenge, "S256", false},
+		{"plain method", testVerifier, testVerifier, "plain", false},
+		{"missing method", testVerifier, challenge, "", false},
+		{"lowercase method", testVerifier, challenge, "s256", false},
+		{"empty verifier", "", pkceChallenge(""), "S256", false},
+		{"verifier too short", "abc", pkceChallenge("abc"), "S256", false},
+		{"verifier too long", strings.Repeat("a", 129), pkceChallenge(strings.Repeat("a", 129)), "S256", false},
+		{"verifier with invalid characters", testVerifier[:42] + "+", pkceChallenge(testVerifier[:42] + "+"), "S256", false},
+	}
+	for _, tc := range cases {
+		if got := verifyPKCE(tc.verifier, tc.challenge, tc.method); got != tc.want {
+			t.Errorf("%s: verifyPKCE = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAuthorizeRejectsNonS256Challenge(t *testing.T) {
+	db := newTestDB(t)
+	_, account := createPublicClient(t, db)
+	app := newOAuthTestApp(db)
+
+	cases := []struct {
+		name      string
+		challenge string
+		method    string
+	}{
+		{"plain method", testVerifier, "plain"},
+		{"missing method", pkceChallenge(testVerifier), ""},
+		{"unknown method", pkceChallenge(testVerifier), "S512"},
+		{"public client without challenge", "", ""},
+	}
+	for _, tc := range cases {
+		query := redirectQuery(t, authorize(t, app, account, authorizeParams(tc.challenge, tc.method)))
+		if query.Get("error") != "invalid_request" || query.Get("state") != "xyz" || query.Get("code") != "" {
+			t.Errorf("%s: redirect query = %v, want invalid_request error with state", tc.name, query)
+		}
+	}
+
+	var count int64
+	db.Model(&AuthorizationCode{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d authorization codes issued for rejected requests", count)
+	}
+}
+
+func TestAuthorizationCodeRequiresVerifier(t *testing.T) {
+	db := newTestDB(t)
+	_, account := createPublicClient(t, db)
+	app := newOAuthTestApp(db)
+
+	query := redirectQuery(t, authorize(t, app, account, authorizeParams(pkceChallenge(testVerifier), "S256")))
+	code := query.Get("code")
+	if code == "" {
+		t.Fatalf("no code in redirect query %v", query)
+	}
+
+	for _, verifier := range []string{"", strings.Replace(testVerifier, "d", "e", 1)} {
+		status, body := exchangeCode(t, app, code, verifier)
+		if status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+			t.Errorf("verifier %q: status %d body %v, want 400 invalid_grant", verifier, status, body)
+		}
+	}
+
+	// Verifier salah tidak menghabiskan kode, verifier yang benar tetap bisa menukarnya sekali
+	status, body := exchangeCode(t, app, code, testVerifier)
+	if status != http.StatusOK || body["access_token"] == nil || body["refresh_token"] == nil {
+		t.Fatalf("exchange with correct verifier: status %d body %v", status, body)
+	}
+	if status, body := exchangeCode(t, app, code, testVerifier); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Errorf("second exchange: status %d body %v, want 400 invalid_grant", status, body)
+	}
+}
+
+func TestAuthorizationCodeWithPlainMethodIsRejected(t *testing.T) {
+	db := newTestDB(t)
+	_, account := createPublicClient(t, db)
+	app := newOAuthTestApp(db)
+
+	// Kode lama yang tersimpan dengan metode plain (sebelum hanya S256 diterima) tidak bisa ditukar
+	rawCode := "legacy-plain-code"
+	challenge, method := testVerifier, "plain"
+	code := AuthorizationCode{
+		CodeHash:            helper.HashToken(rawCode),
+		ClientId:            testClientID,
+		UserId:              account.Id,
+		RedirectURI:         testRedirectURI,
+		Scope:               "profile",
+		CodeChallenge:       &challenge,
+		CodeChallengeMethod: &method,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	}
+	if err := db.Create(&code).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if status, body := exchangeCode(t, app, rawCode, testVerifier); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Errorf("status %d body %v, want 400 invalid_grant", status, body)
+	}
+}
+
+func TestAuthorizationCodeRedirectURIOnlyRequiredWhenSent(t *testing.T) {
+	db := newTestDB(t)
+	_, account := createPublicClient(t, db)
+	app := newOAuthTestApp(db)
+
+	tokenForm := func(code string, redirectURI string) url.Values {
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {testClientID},
+			"code":          {code},
+			"code_verifier": {testVerifier},
+		}
+		if redirectURI != "" {
+			form.Set("redirect_uri", redirectURI)
+		}
+		return form
+	}
+
+	// redirect_uri dikirim saat authorize: wajib dikirim lagi saat penukaran kode
+	explicit := redirectQuery(t, authorize(t, app, account, authorizeParams(pkceChallenge(testVerifier), "S256"))).Get("code")
+	if status, body := postToken(t, app, tokenForm(explicit, "")); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Errorf("explicit redirect_uri omitted at token step: status %d body %v, want 400 invalid_grant", status, body)
+	}
+
+	// redirect_uri tidak dikirim (client hanya punya satu URI): boleh tidak dikirim, jika dikirim harus sama
+	params := authorizeParams(pkceChallenge(testVerifier), "S256")
+	params.Del("redirect_uri")
+	omitted := redirectQuery(t, authorize(t, app, account, params)).Get("code")
+	if status, body := postToken(t, app, tokenForm(omitted, "https://evil.example.com/callback")); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Errorf("different redirect_uri at token step: status %d body %v, want 400 invalid_grant", status, body)
+	}
+	if status, body := postToken(t, app, tokenForm(omitted, "")); status != http.StatusOK || body["access_token"] == nil {
+		t.Errorf("defaulted redirect_uri omitted at token step: status %d body %v, want 200", status, body)
+	}
+}
+
+func TestLoginOffersPasskeyStepForPasskeyOnlyAccount(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", helper.PasswordHashBcrypt)
+	t.Setenv("BCRYPT_COST", "4")
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&user.WebAuthnCredential{}, &auth.LoginFailure{}, &auth.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	createPublicClient(t, db)
+
+	hashedPassword, err := helper.HashPassword("Tangerine-Kettle-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := user.User{Username: "siti", Role: user.USER, IsActive: true, Password: &hashedPassword}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	credential := user.WebAuthnCredential{UserId: account.Id, CredentialId: []byte("credential-1"), PublicKey: []byte{0}}
+	if err := db.Create(&credential).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewOAuthService(db)
+	app := fiber.New()
+	app.Post("/oauth/authorize", func(ctx *fiber.Ctx) error {
+		return ctx.JSON(service.Authorize(ctx))
+	})
+
+	form := authorizeParams(pkceChallenge(testVerifier), "S256")
+	form.Set("csrf_token", "csrf")
+	form.Set("action", "login")
+	form.Set("username", "siti")
+	form.Set("password", "Tangerine-Kettle-42")
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: csrfCookie, Value: "csrf"})
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result AuthorizeResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	page := result.Page
+	if result.Status != http.StatusOK || page == nil || page.Step != "passkey" {
+		t.Fatalf("login result = %+v, want passkey step", result)
+	}
+	if page.PasskeySession == "" || !strings.Contains(page.PasskeyOptions, `"challenge"`) {
+		t.Errorf("passkey step without ceremony: session %q options %q", page.PasskeySession, page.PasskeyOptions)
+	}
+}
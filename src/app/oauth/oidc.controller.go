@@ -0,0 +1,29 @@
+package oauth
+
+import "github.com/gofiber/fiber/v2"
+
+type OIDCController struct {
+	Service OIDCService
+}
+
+func NewOIDCController(service OIDCService) *OIDCController {
+	return &OIDCController{Service: service}
+}
+
+// Discovery mengembalikan dokumen openid-configuration
+func (oc *OIDCController) Discovery(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(oc.Service.Discovery(ctx))
+}
+
+// UserInfo mengembalikan claims user dalam format OIDC (bukan APIResponse)
+func (oc *OIDCController) UserInfo(ctx *fiber.Ctx) error {
+	claims, oauthErr := oc.Service.UserInfo(ctx)
+	if oauthErr != nil {
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="`+oauthErr.Code+`", error_description="`+oauthErr.ErrorDescription+`"`)
+		return ctx.Status(oauthErr.Status).JSON(oauthErr)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(claims)
+}
@@ -0,0 +1,132 @@
+package oauth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// OIDCService interface
+type OIDCService interface {
+	Discovery(ctx *fiber.Ctx) fiber.Map
+	UserInfo(ctx *fiber.Ctx) (fiber.Map, *OAuthError)
+}
+
+// OIDCServiceImpl struct
+type OIDCServiceImpl struct {
+	DB *gorm.DB
+}
+
+// Konstruktor untuk OIDCService
+func NewOIDCService(db *gorm.DB) OIDCService {
+	return &OIDCServiceImpl{DB: db}
+}
+
+// issuer URL penerbit token OIDC (OIDC_ISSUER, default APP_URL), tanpa garis miring di akhir
+func issuer() string {
+	return strings.TrimRight(helper.GetEnv("OIDC_ISSUER", helper.GetEnv("APP_URL", "http://localhost:3000")), "/")
+}
+
+// Implementasi Discovery (metadata OpenID Provider)
+func (o *OIDCServiceImpl) Discovery(ctx *fiber.Ctx) fiber.Map {
+	base := issuer()
+	algorithm, err := helper.ActiveSigningAlgorithm()
+	if err != nil {
+		algorithm = "RS256"
+	}
+
+	return fiber.Map{
+		"issuer":                                base,
+		"authorization_endpoint":                base + "/oauth/authorize",
+		"token_endpoint":                        base + "/oauth/token",
+		"userinfo_endpoint":                     base + "/oauth/userinfo",
+		"jwks_uri":                              base + "/.well-known/jwks.json",
+		"introspection_endpoint":                base + "/oauth/introspect",
+		"revocation_endpoint":                   base + "/oauth/revoke",
+		"device_authorization_endpoint":         base + "/oauth/device_authorization",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials", deviceCodeGrantType},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{algorithm},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce",
+			"name", "preferred_username", "updated_at", "email", "email_verified",
+		},
+	}
+}
+
+// Implementasi UserInfo (claims user pemilik access token, sesuai scope yang diberikan)
+func (o *OIDCServiceImpl) UserInfo(ctx *fiber.Ctx) (fiber.Map, *OAuthError) {
+	// Scope openid sudah dicek oleh middleware.RequireScope di route
+	scope, _ := ctx.Locals("scope").(string)
+
+	var foundUser user.User
+	userID := int64(ctx.Locals("user_id").(float64))
+	if err := o.DB.Where("id = ? AND is_active = ?", userID, true).First(&foundUser).Error; err != nil {
+		return nil, oauthError(http.StatusUnauthorized, "invalid_token", "User is no longer active")
+	}
+
+	claims := fiber.Map{}
+	for key, value := range userClaims(foundUser, scope) {
+		claims[key] = value
+	}
+	return claims, nil
+}
+
+// issueIDToken membuat ID token bertanda tangan untuk client (OIDC Core section 2)
+func issueIDToken(u user.User, clientID string, scope string, nonce string, authTime *time.Time) (string, error) {
+	now := time.Now()
+	claims := userClaims(u, scope)
+	claims["jti"] = uuid.NewString()
+	claims["token_use"] = "id" // Ditolak AuthMiddleware, ID token bukan access token
+	claims["iss"] = issuer()
+	claims["aud"] = clientID
+	claims["azp"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(helper.AccessTokenTTL()).Unix()
+	if authTime != nil {
+		claims["auth_time"] = authTime.Unix()
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return helper.SignJWT(claims)
+}
+
+// userClaims menyusun claims standar OIDC dari user.User berdasarkan scope
+func userClaims(u user.User, scope string) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub": strconv.FormatInt(u.Id, 10),
+	}
+
+	if hasScope(scope, "profile") {
+		claims["preferred_username"] = u.Username
+		claims["updated_at"] = u.UpdatedAt.Unix()
+		if u.Fullname != nil && *u.Fullname != "" {
+			claims["name"] = *u.Fullname
+		}
+	}
+
+	if hasScope(scope, "email") && u.Email != nil {
+		claims["email"] = *u.Email
+		claims["email_verified"] = u.EmailVerifiedAt != nil
+	}
+	return claims
+}
+
+// hasScope mengecek apakah daftar scope (dipisah spasi) berisi scope tertentu
+func hasScope(scope string, target string) bool {
+	return contains(strings.Fields(scope), target)
+}
@@ -0,0 +1,44 @@
+package oauth
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.SetSecrets()
+	os.Exit(m.Run())
+}
+
+// clientsTableSQL tabel oauth_clients versi SQLite; tag updated_at memakai ON UPDATE khusus MySQL
+// sehingga tidak bisa di-AutoMigrate. Kolom harus mengikuti Client.
+const clientsTableSQL = `CREATE TABLE oauth_clients (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	client_id TEXT NOT NULL UNIQUE,
+	secret_hash TEXT,
+	name TEXT NOT NULL,
+	redirect_uris TEXT,
+	scopes TEXT,
+	grant_types TEXT,
+	public NUMERIC DEFAULT false,
+	trusted NUMERIC DEFAULT false,
+	service_account_id INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// newTestDB database SQLite in-memory dengan tabel milik paket oauth
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := testutil.NewDB(t, &AuthorizationCode{}, &auth.RefreshToken{})
+	if err := db.Exec(clientsTableSQL).Error; err != nil {
+		t.Fatalf("create oauth_clients: %v", err)
+	}
+	return db
+}
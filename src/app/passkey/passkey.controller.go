@@ -0,0 +1,49 @@
+package passkey
+
+import "github.com/gofiber/fiber/v2"
+
+// PasskeyController struct
+type PasskeyController struct {
+	Service PasskeyService
+}
+
+// NewPasskeyController adalah constructor untuk PasskeyController
+func NewPasskeyController(service PasskeyService) *PasskeyController {
+	return &PasskeyController{Service: service}
+}
+
+// BeginRegistration memulai ceremony registrasi passkey
+func (pc *PasskeyController) BeginRegistration(ctx *fiber.Ctx) error {
+	response := pc.Service.BeginRegistration(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// FinishRegistration menyelesaikan ceremony registrasi passkey
+func (pc *PasskeyController) FinishRegistration(ctx *fiber.Ctx) error {
+	response := pc.Service.FinishRegistration(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// BeginLogin memulai ceremony login passkey
+func (pc *PasskeyController) BeginLogin(ctx *fiber.Ctx) error {
+	response := pc.Service.BeginLogin(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// FinishLogin menyelesaikan ceremony login passkey
+func (pc *PasskeyController) FinishLogin(ctx *fiber.Ctx) error {
+	response := pc.Service.FinishLogin(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// ListCredentials menangani pengambilan daftar passkey user
+func (pc *PasskeyController) ListCredentials(ctx *fiber.Ctx) error {
+	response := pc.Service.ListCredentials(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// DeleteCredential menangani penghapusan passkey user
+func (pc *PasskeyController) DeleteCredential(ctx *fiber.Ctx) error {
+	response := pc.Service.DeleteCredential(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -0,0 +1,17 @@
+package passkey
+
+import "encoding/json"
+
+type RegisterBeginDTO struct {
+	Name string `json:"name" validate:"max=100"`
+}
+
+type LoginBeginDTO struct {
+	Username string `json:"username"`
+	MFAToken string `json:"mfa_token"`
+}
+
+type FinishDTO struct {
+	SessionId  string          `json:"session_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
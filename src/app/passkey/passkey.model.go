@@ -0,0 +1,114 @@
+package passkey
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/achyar10/go-auth/src/app/user"
+)
+
+// webAuthnUser adapter user.User agar memenuhi interface webauthn.User
+type webAuthnUser struct {
+	user        user.User
+	credentials []user.WebAuthnCredential
+}
+
+func (w *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(w.user.Id, 10))
+}
+
+func (w *webAuthnUser) WebAuthnName() string {
+	return w.user.Username
+}
+
+func (w *webAuthnUser) WebAuthnDisplayName() string {
+	if w.user.Fullname != nil && *w.user.Fullname != "" {
+		return *w.user.Fullname
+	}
+	return w.user.Username
+}
+
+func (w *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(w.credentials))
+	for _, credential := range w.credentials {
+		credentials = append(credentials, toWebAuthnCredential(credential))
+	}
+	return credentials
+}
+
+// toWebAuthnCredential mengubah baris database menjadi webauthn.Credential
+func toWebAuthnCredential(credential user.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.Split(credential.Transports, ",") {
+		if transport != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              credential.CredentialId,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    credential.AAGUID,
+			SignCount: credential.SignCount,
+		},
+	}
+}
+
+// ceremony menyimpan state sementara antara tahap begin dan finish
+type ceremony struct {
+	Purpose  string // "register", "login" atau "mfa"
+	UserId   int64
+	Name     string
+	MFAToken string
+	Session  webauthn.SessionData
+	Expires  time.Time
+}
+
+// ceremonyStore penyimpanan ceremony di memori dengan masa berlaku
+type ceremonyStore struct {
+	mu    sync.Mutex
+	items map[string]ceremony
+}
+
+func newCeremonyStore() *ceremonyStore {
+	return &ceremonyStore{items: make(map[string]ceremony)}
+}
+
+// Put menyimpan ceremony dan membersihkan entri yang sudah kedaluwarsa
+func (s *ceremonyStore) Put(id string, item ceremony) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.items {
+		if now.After(existing.Expires) {
+			delete(s.items, key)
+		}
+	}
+	s.items[id] = item
+}
+
+// Take mengambil ceremony sekali pakai (langsung dihapus)
+func (s *ceremonyStore) Take(id string) (ceremony, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	delete(s.items, id)
+	if !ok || time.Now().After(item.Expires) {
+		return ceremony{}, false
+	}
+	return item, true
+}
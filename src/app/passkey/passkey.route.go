@@ -0,0 +1,21 @@
+package passkey
+
+import (
+	"github.com/achyar10/go-auth/src/middleware"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SetupRoutes mengatur routing WebAuthn / passkey
+func SetupRoutes(app *fiber.App, db *gorm.DB) {
+	passkeyService := NewPasskeyService(db)
+	passkeyController := NewPasskeyController(passkeyService)
+
+	passkeyRoutes := app.Group("/auth/webauthn")
+	passkeyRoutes.Post("/register/begin", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, passkeyController.BeginRegistration)
+	passkeyRoutes.Post("/register/finish", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, passkeyController.FinishRegistration)
+	passkeyRoutes.Post("/login/begin", passkeyController.BeginLogin)
+	passkeyRoutes.Post("/login/finish", passkeyController.FinishLogin)
+	passkeyRoutes.Get("/credentials", middleware.AuthMiddleware, middleware.RequireUser, passkeyController.ListCredentials)
+	passkeyRoutes.Delete("/credentials/:id", middleware.AuthMiddleware, middleware.RequireUser, middleware.ForbidImpersonation, passkeyController.DeleteCredential)
+}
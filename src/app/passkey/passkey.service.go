@@ -0,0 +1,464 @@
+package passkey
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// ceremonyTTL masa berlaku satu ceremony registrasi / login
+const ceremonyTTL = 5 * time.Minute
+
+// PasskeyService interface
+type PasskeyService interface {
+	BeginRegistration(ctx *fiber.Ctx) utility.APIResponse
+	FinishRegistration(ctx *fiber.Ctx) utility.APIResponse
+	BeginLogin(ctx *fiber.Ctx) utility.APIResponse
+	FinishLogin(ctx *fiber.Ctx) utility.APIResponse
+	ListCredentials(ctx *fiber.Ctx) utility.APIResponse
+	DeleteCredential(ctx *fiber.Ctx) utility.APIResponse
+	BeginMFA(mfaToken string) (*protocol.CredentialAssertion, string, error)
+	FinishMFA(sessionID string, credential []byte) (user.User, error)
+}
+
+// ErrPasskeyMFAFailed verifikasi passkey sebagai faktor kedua gagal (detail dicatat di log)
+var ErrPasskeyMFAFailed = errors.New("passkey verification failed")
+
+// PasskeyServiceImpl struct
+type PasskeyServiceImpl struct {
+	DB         *gorm.DB
+	Validate   *validator.Validate
+	WebAuthn   *webauthn.WebAuthn
+	Ceremonies *ceremonyStore
+}
+
+// Konstruktor untuk PasskeyService, konfigurasi relying party diambil dari env
+func NewPasskeyService(db *gorm.DB) PasskeyService {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          helper.GetEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPDisplayName: helper.GetEnv("WEBAUTHN_RP_NAME", "go-auth"),
+		RPOrigins:     strings.Split(helper.GetEnv("WEBAUTHN_RP_ORIGINS", "http://localhost:3000"), ","),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyTTL},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyTTL},
+		},
+	})
+	if err != nil {
+		log.Fatal("Konfigurasi WebAuthn tidak valid:", err)
+	}
+
+	return &PasskeyServiceImpl{
+		DB:         db,
+		Validate:   validator.New(),
+		WebAuthn:   webAuthn,
+		Ceremonies: newCeremonyStore(),
+	}
+}
+
+// Implementasi BeginRegistration (opsi pembuatan kredensial untuk user yang sedang login)
+func (p *PasskeyServiceImpl) BeginRegistration(ctx *fiber.Ctx) utility.APIResponse {
+	var dto RegisterBeginDTO
+
+	// Body bersifat opsional
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&dto); err != nil {
+			return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+		}
+	}
+
+	// Validasi DTO
+	if err := p.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	account, err := p.loadUser(int64(ctx.Locals("user_id").(float64)))
+	if err != nil {
+		return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+	}
+
+	// Kredensial yang sudah terdaftar dikecualikan agar authenticator yang sama tidak didaftarkan dua kali
+	var exclusions []protocol.CredentialDescriptor
+	for _, credential := range account.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, err := p.WebAuthn.BeginRegistration(account,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to begin registration", []string{err.Error()})
+	}
+
+	sessionID, err := p.storeCeremony(ceremony{
+		Purpose: "register",
+		UserId:  account.user.Id,
+		Name:    dto.Name,
+		Session: *session,
+	})
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to begin registration", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", fiber.Map{
+		"session_id": sessionID,
+		"options":    options,
+	})
+}
+
+// Implementasi FinishRegistration (verifikasi attestation lalu simpan kredensial)
+func (p *PasskeyServiceImpl) FinishRegistration(ctx *fiber.Ctx) utility.APIResponse {
+	var dto FinishDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := p.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	userID := int64(ctx.Locals("user_id").(float64))
+	state, ok := p.Ceremonies.Take(dto.SessionId)
+	if !ok || state.Purpose != "register" || state.UserId != userID {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired registration session", nil)
+	}
+
+	account, err := p.loadUser(userID)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(dto.Credential)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid credential", []string{protocolError(err)})
+	}
+
+	credential, err := p.WebAuthn.CreateCredential(account, state.Session, parsed)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Failed to verify credential", []string{protocolError(err)})
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	name := state.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	record := user.WebAuthnCredential{
+		UserId:          userID,
+		Name:            name,
+		CredentialId:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := p.DB.Create(&record).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to store credential", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusCreated, "Passkey registered successfully", record)
+}
+
+// Implementasi BeginLogin. Tiga mode:
+//   - mfa_token diisi: passkey sebagai faktor kedua setelah password
+//   - username diisi: passkey sebagai faktor pertama untuk user tertentu
+//   - kosong: discoverable login (passkey memilih akun sendiri)
+func (p *PasskeyServiceImpl) BeginLogin(ctx *fiber.Ctx) utility.APIResponse {
+	var dto LoginBeginDTO
+
+	// Body bersifat opsional
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&dto); err != nil {
+			return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+		}
+	}
+
+	var (
+		options *protocol.CredentialAssertion
+		session *webauthn.SessionData
+		state   ceremony
+		err     error
+	)
+
+	switch {
+	case dto.MFAToken != "":
+		challengeUser, _, parseErr := auth.ParseMFAChallenge(p.DB, dto.MFAToken)
+		if parseErr != nil {
+			return utility.ErrorResponse(http.StatusUnauthorized, "Invalid or expired MFA token", nil)
+		}
+		account, loadErr := p.loadUser(challengeUser.Id)
+		if loadErr != nil || len(account.credentials) == 0 {
+			return utility.ErrorResponse(http.StatusBadRequest, "No passkey registered", nil)
+		}
+		options, session, err = p.WebAuthn.BeginLogin(account)
+		state = ceremony{Purpose: "mfa", UserId: account.user.Id, MFAToken: dto.MFAToken}
+
+	case dto.Username != "":
+		var foundUser user.User
+		if findErr := p.DB.Where("username = ?", dto.Username).First(&foundUser).Error; findErr != nil {
+			return utility.ErrorResponse(http.StatusUnauthorized, "Passkey login is not available", nil)
+		}
+		account, loadErr := p.loadUser(foundUser.Id)
+		if loadErr != nil || len(account.credentials) == 0 {
+			return utility.ErrorResponse(http.StatusUnauthorized, "Passkey login is not available", nil)
+		}
+		options, session, err = p.WebAuthn.BeginLogin(account, webauthn.WithUserVerification(protocol.VerificationRequired))
+		state = ceremony{Purpose: "login", UserId: account.user.Id}
+
+	default:
+		options, session, err = p.WebAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		state = ceremony{Purpose: "login"}
+	}
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to begin login", []string{err.Error()})
+	}
+
+	state.Session = *session
+	sessionID, err := p.storeCeremony(state)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to begin login", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", fiber.Map{
+		"session_id": sessionID,
+		"options":    options,
+	})
+}
+
+// Implementasi FinishLogin (verifikasi assertion, cek sign count, lalu terbitkan sesi)
+func (p *PasskeyServiceImpl) FinishLogin(ctx *fiber.Ctx) utility.APIResponse {
+	var dto FinishDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := p.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	state, ok := p.Ceremonies.Take(dto.SessionId)
+	if !ok || (state.Purpose != "login" && state.Purpose != "mfa") {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid or expired login session", nil)
+	}
+
+	account, response := p.verifyAssertion(state, dto.Credential)
+	if response != nil {
+		return *response
+	}
+
+	responseData, err := auth.IssueSession(p.DB, account.user)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create session", []string{err.Error()})
+	}
+	return utility.SuccessResponse(http.StatusOK, "Login success", responseData)
+}
+
+// BeginMFA memulai ceremony passkey sebagai faktor kedua untuk token tantangan MFA. Dipakai halaman
+// login OAuth yang merender opsi ini langsung; ceremony harus diselesaikan dengan FinishMFA.
+func (p *PasskeyServiceImpl) BeginMFA(mfaToken string) (*protocol.CredentialAssertion, string, error) {
+	challengeUser, _, err := auth.ParseMFAChallenge(p.DB, mfaToken)
+	if err != nil {
+		return nil, "", err
+	}
+	account, err := p.loadUser(challengeUser.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(account.credentials) == 0 {
+		return nil, "", errors.New("no passkey registered")
+	}
+
+	options, session, err := p.WebAuthn.BeginLogin(account)
+	if err != nil {
+		return nil, "", err
+	}
+	sessionID, err := p.storeCeremony(ceremony{Purpose: "mfa", UserId: account.user.Id, MFAToken: mfaToken, Session: *session})
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sessionID, nil
+}
+
+// FinishMFA memverifikasi assertion dari ceremony BeginMFA dan mengembalikan user-nya; sesi dibuat oleh pemanggil
+func (p *PasskeyServiceImpl) FinishMFA(sessionID string, credential []byte) (user.User, error) {
+	state, ok := p.Ceremonies.Take(sessionID)
+	if !ok || state.Purpose != "mfa" {
+		return user.User{}, ErrPasskeyMFAFailed
+	}
+
+	account, response := p.verifyAssertion(state, credential)
+	if response != nil {
+		log.Printf("Verifikasi passkey MFA untuk user %d gagal: %s %v", state.UserId, response.Message, response.Errors)
+		return user.User{}, ErrPasskeyMFAFailed
+	}
+	return account.user, nil
+}
+
+// verifyAssertion memverifikasi assertion untuk ceremony login / mfa: cek sign count, perbarui kredensial,
+// dan habiskan tantangan MFA. Response tidak nil berarti verifikasi gagal.
+func (p *PasskeyServiceImpl) verifyAssertion(state ceremony, rawCredential []byte) (*webAuthnUser, *utility.APIResponse) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(rawCredential)
+	if err != nil {
+		return nil, errorResponse(http.StatusBadRequest, "Invalid credential", []string{protocolError(err)})
+	}
+
+	var (
+		account    *webAuthnUser
+		credential *webauthn.Credential
+	)
+	if state.UserId != 0 {
+		account, err = p.loadUser(state.UserId)
+		if err != nil {
+			return nil, errorResponse(http.StatusUnauthorized, "Passkey login failed", nil)
+		}
+		credential, err = p.WebAuthn.ValidateLogin(account, state.Session, parsed)
+	} else {
+		var discovered webauthn.User
+		discovered, credential, err = p.WebAuthn.ValidatePasskeyLogin(p.discoverUser, state.Session, parsed)
+		if err == nil {
+			account = discovered.(*webAuthnUser)
+		}
+	}
+	if err != nil {
+		return nil, errorResponse(http.StatusUnauthorized, "Passkey login failed", []string{protocolError(err)})
+	}
+
+	// Sign count tidak naik: kemungkinan authenticator digandakan, tolak login
+	if credential.Authenticator.CloneWarning {
+		log.Printf("WebAuthn sign count regression terdeteksi untuk user %d (credential %x)", account.user.Id, credential.ID)
+		return nil, errorResponse(http.StatusUnauthorized, "Authenticator sign count regression detected", nil)
+	}
+
+	if account.user.IsServiceAccount() {
+		return nil, errorResponse(http.StatusForbidden, "Service accounts cannot sign in interactively", nil)
+	}
+
+	if auth.EmailVerificationRequired(account.user) {
+		return nil, errorResponse(http.StatusForbidden, "Email address has not been verified", nil)
+	}
+
+	if err := p.DB.Model(&user.WebAuthnCredential{}).
+		Where("credential_id = ? AND user_id = ?", credential.ID, account.user.Id).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": time.Now(),
+		}).Error; err != nil {
+		return nil, errorResponse(http.StatusInternalServerError, "Failed to update credential", []string{err.Error()})
+	}
+
+	if state.Purpose == "mfa" {
+		// Token tantangan harus masih berlaku saat ceremony selesai
+		_, claims, err := auth.ParseMFAChallenge(p.DB, state.MFAToken)
+		if err != nil {
+			return nil, errorResponse(http.StatusUnauthorized, "Invalid or expired MFA token", nil)
+		}
+		auth.ConsumeMFAChallenge(claims, account.user.Id)
+	}
+
+	return account, nil
+}
+
+// errorResponse pointer ke utility.ErrorResponse
+func errorResponse(status int, message string, errs []string) *utility.APIResponse {
+	response := utility.ErrorResponse(status, message, errs)
+	return &response
+}
+
+// Implementasi ListCredentials (passkey milik user yang sedang login)
+func (p *PasskeyServiceImpl) ListCredentials(ctx *fiber.Ctx) utility.APIResponse {
+	var credentials []user.WebAuthnCredential
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	if err := p.DB.Where("user_id = ?", userID).Order("id").Find(&credentials).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve credentials", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", credentials)
+}
+
+// Implementasi DeleteCredential (menghapus passkey milik user yang sedang login)
+func (p *PasskeyServiceImpl) DeleteCredential(ctx *fiber.Ctx) utility.APIResponse {
+	id := ctx.Params("id")
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	result := p.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&user.WebAuthnCredential{})
+	if result.Error != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to delete credential", []string{result.Error.Error()})
+	}
+	if result.RowsAffected == 0 {
+		return utility.ErrorResponse(http.StatusNotFound, "Credential not found", nil)
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Credential deleted successfully", nil)
+}
+
+// loadUser memuat user beserta kredensial WebAuthn-nya
+func (p *PasskeyServiceImpl) loadUser(userID int64) (*webAuthnUser, error) {
+	var account webAuthnUser
+	if err := p.DB.First(&account.user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := p.DB.Where("user_id = ?", userID).Find(&account.credentials).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// discoverUser mencari user dari user handle pada discoverable login
+func (p *PasskeyServiceImpl) discoverUser(rawID, userHandle []byte) (webauthn.User, error) {
+	userID, err := strconv.ParseInt(string(userHandle), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid user handle")
+	}
+	return p.loadUser(userID)
+}
+
+// storeCeremony menyimpan state ceremony dan mengembalikan session id acak
+func (p *PasskeyServiceImpl) storeCeremony(state ceremony) (string, error) {
+	sessionID, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	state.Expires = time.Now().Add(ceremonyTTL)
+	p.Ceremonies.Put(sessionID, state)
+	return sessionID, nil
+}
+
+// protocolError mengambil detail error dari library WebAuthn
+func protocolError(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.Details != "" {
+		return protocolErr.Details
+	}
+	return err.Error()
+}
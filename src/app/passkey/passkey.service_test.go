@@ -0,0 +1,369 @@
+package passkey
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/user"
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+// Flag authenticator data (WebAuthn section 6.1)
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// testOrigin origin default dari WEBAUTHN_RP_ORIGINS
+const testOrigin = "http://localhost:3000"
+
+// softAuthenticator authenticator WebAuthn di memori (kunci ES256, attestation "none")
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+	userHandle   []byte
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{rpID: "localhost", origin: testOrigin, credentialID: credentialID, key: key}
+}
+
+// authenticatorData rpIdHash | flags | signCount | attestedCredentialData
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// clientDataJSON data yang dibuat browser untuk ceremony
+func (a *softAuthenticator) clientDataJSON(ceremonyType string, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": a.origin})
+	return data
+}
+
+// create membuat respons navigator.credentials.create() untuk challenge registrasi
+func (a *softAuthenticator) create(t *testing.T, challenge string) json.RawMessage {
+	t.Helper()
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attested := make([]byte, 16) // AAGUID kosong
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(flagUserPresent|flagUserVerified|flagAttestedCredData, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mustJSON(t, map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(a.clientDataJSON("webauthn.create", challenge)),
+			"attestationObject": encode(attestationObject),
+		},
+	})
+}
+
+// get membuat respons navigator.credentials.get() yang ditandatangani untuk challenge login
+func (a *softAuthenticator) get(t *testing.T, challenge string) json.RawMessage {
+	t.Helper()
+	authData := a.authenticatorData(flagUserPresent|flagUserVerified, nil)
+	clientData := a.clientDataJSON("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mustJSON(t, map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.userHandle),
+		},
+	})
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func mustJSON(t *testing.T, value any) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// ceremonyResponse bentuk respons endpoint begin / finish yang dipakai test
+type ceremonyResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		SessionId string `json:"session_id"`
+		Options   struct {
+			PublicKey struct {
+				Challenge string `json:"challenge"`
+			} `json:"publicKey"`
+		} `json:"options"`
+		Token string `json:"access_token"`
+	} `json:"data"`
+}
+
+// passkeyTestApp route passkey tanpa AuthMiddleware; user yang "login" diambil dari header X-User-Id
+func passkeyTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
+	t.Helper()
+	db := testutil.NewDB(t, &user.WebAuthnCredential{}, &auth.RefreshToken{})
+
+	controller := NewPasskeyController(NewPasskeyService(db))
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		if id, err := strconv.ParseInt(ctx.Get("X-User-Id"), 10, 64); err == nil {
+			ctx.Locals("user_id", float64(id))
+		}
+		return ctx.Next()
+	})
+	app.Post("/register/begin", controller.BeginRegistration)
+	app.Post("/register/finish", controller.FinishRegistration)
+	app.Post("/login/begin", controller.BeginLogin)
+	app.Post("/login/finish", controller.FinishLogin)
+	return app, db
+}
+
+// call mengirim body JSON ke path dan mengembalikan status beserta respons
+func call(t *testing.T, app *fiber.App, path string, userID int64, body any) (int, ceremonyResponse) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(mustJSON(t, body)))
+	request.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		request.Header.Set("X-User-Id", strconv.FormatInt(userID, 10))
+	}
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var decoded ceremonyResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, decoded
+}
+
+// registerPasskey mendaftarkan authenticator untuk user baru lewat ceremony registrasi
+func registerPasskey(t *testing.T, app *fiber.App, db *gorm.DB) (*softAuthenticator, user.User) {
+	t.Helper()
+	account := user.User{Username: "budi", Role: user.USER}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	authenticator := newSoftAuthenticator(t)
+	authenticator.userHandle = []byte(strconv.FormatInt(account.Id, 10))
+
+	status, begin := call(t, app, "/register/begin", account.Id, map[string]string{"name": "Laptop"})
+	if status != http.StatusOK {
+		t.Fatalf("register begin: status %d (%s)", status, begin.Message)
+	}
+	status, finish := call(t, app, "/register/finish", account.Id, map[string]any{
+		"session_id": begin.Data.SessionId,
+		"credential": authenticator.create(t, begin.Data.Options.PublicKey.Challenge),
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("register finish: status %d (%s)", status, finish.Message)
+	}
+	return authenticator, account
+}
+
+// login menjalankan ceremony login discoverable dengan authenticator
+func login(t *testing.T, app *fiber.App, authenticator *softAuthenticator) (int, ceremonyResponse) {
+	t.Helper()
+	status, begin := call(t, app, "/login/begin", 0, map[string]string{})
+	if status != http.StatusOK {
+		t.Fatalf("login begin: status %d (%s)", status, begin.Message)
+	}
+	return call(t, app, "/login/finish", 0, map[string]any{
+		"session_id": begin.Data.SessionId,
+		"credential": authenticator.get(t, begin.Data.Options.PublicKey.Challenge),
+	})
+}
+
+func TestPasskeyRegistrationStoresCredential(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	authenticator, account := registerPasskey(t, app, db)
+
+	var stored user.WebAuthnCredential
+	if err := db.Where("user_id = ?", account.Id).First(&stored).Error; err != nil {
+		t.Fatalf("credential not stored: %v", err)
+	}
+	if !bytes.Equal(stored.CredentialId, authenticator.credentialID) || stored.Name != "Laptop" || stored.AttestationType != "none" {
+		t.Errorf("stored credential = %+v", stored)
+	}
+}
+
+func TestPasskeyRegistrationRejectsWrongOrigin(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	account := user.User{Username: "budi", Role: user.USER}
+	db.Create(&account)
+	authenticator := newSoftAuthenticator(t)
+	authenticator.origin = "https://evil.example"
+
+	_, begin := call(t, app, "/register/begin", account.Id, map[string]string{})
+	status, _ := call(t, app, "/register/finish", account.Id, map[string]any{
+		"session_id": begin.Data.SessionId,
+		"credential": authenticator.create(t, begin.Data.Options.PublicKey.Challenge),
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("wrong origin: status %d, want 400", status)
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	authenticator, account := registerPasskey(t, app, db)
+
+	authenticator.signCount = 1
+	status, response := login(t, app, authenticator)
+	if status != http.StatusOK || response.Data.Token == "" {
+		t.Fatalf("login: status %d (%s), want 200 with access token", status, response.Message)
+	}
+
+	token, err := helper.ValidateJWT(response.Data.Token)
+	if err != nil {
+		t.Fatalf("invalid access token: %v", err)
+	}
+	if userID, _ := token.Claims.(jwt.MapClaims)["user_id"].(float64); int64(userID) != account.Id {
+		t.Fatalf("access token user_id = %v, want %d", userID, account.Id)
+	}
+
+	var stored user.WebAuthnCredential
+	db.Where("user_id = ?", account.Id).First(&stored)
+	if stored.SignCount != 1 || stored.LastUsedAt == nil {
+		t.Errorf("after login: sign_count=%d last_used_at=%v, want 1 and set", stored.SignCount, stored.LastUsedAt)
+	}
+}
+
+func TestPasskeyLoginRejectsBadSignature(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	authenticator, _ := registerPasskey(t, app, db)
+
+	// Kunci lain dengan credential id yang sama: tanda tangan tidak cocok dengan public key tersimpan
+	other := newSoftAuthenticator(t)
+	authenticator.key = other.key
+	authenticator.signCount = 1
+	if status, _ := login(t, app, authenticator); status != http.StatusUnauthorized {
+		t.Fatalf("bad signature: status %d, want 401", status)
+	}
+}
+
+func TestPasskeyLoginRejectsSignCountRegression(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	authenticator, _ := registerPasskey(t, app, db)
+
+	authenticator.signCount = 5
+	if status, response := login(t, app, authenticator); status != http.StatusOK {
+		t.Fatalf("first login: status %d (%s)", status, response.Message)
+	}
+
+	// Counter tidak naik (authenticator hasil kloning)
+	status, response := login(t, app, authenticator)
+	if status != http.StatusUnauthorized || response.Message != "Authenticator sign count regression detected" {
+		t.Fatalf("repeated sign count: status %d (%s), want 401 sign count regression", status, response.Message)
+	}
+
+	authenticator.signCount = 3
+	if status, _ := login(t, app, authenticator); status != http.StatusUnauthorized {
+		t.Fatalf("lower sign count: status %d, want 401", status)
+	}
+
+	authenticator.signCount = 6
+	if status, response := login(t, app, authenticator); status != http.StatusOK {
+		t.Fatalf("increasing sign count: status %d (%s), want 200", status, response.Message)
+	}
+}
+
+func TestPasskeyMFA(t *testing.T) {
+	app, db := passkeyTestApp(t)
+	authenticator, account := registerPasskey(t, app, db)
+	service := NewPasskeyService(db)
+
+	mfaToken, err := helper.GeneratePurposeToken("mfa", account.Id, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, sessionID, err := service.BeginMFA(mfaToken)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if len(options.Response.AllowedCredentials) != 1 {
+		t.Fatalf("allowCredentials = %d, want 1", len(options.Response.AllowedCredentials))
+	}
+
+	authenticator.signCount = 1
+	credential := authenticator.get(t, encode(options.Response.Challenge))
+	verified, err := service.FinishMFA(sessionID, credential)
+	if err != nil || verified.Id != account.Id {
+		t.Fatalf("finish: user %d, err %v, want user %d", verified.Id, err, account.Id)
+	}
+
+	if _, err := service.FinishMFA(sessionID, credential); err == nil {
+		t.Error("ceremony accepted twice")
+	}
+}
@@ -0,0 +1,31 @@
+package user
+
+import "github.com/gofiber/fiber/v2"
+
+// TokenController struct
+type TokenController struct {
+	Service TokenService
+}
+
+// NewTokenController adalah constructor untuk TokenController
+func NewTokenController(service TokenService) *TokenController {
+	return &TokenController{Service: service}
+}
+
+// Create menangani pembuatan personal access token
+func (tc *TokenController) Create(ctx *fiber.Ctx) error {
+	response := tc.Service.Create(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// List menangani pengambilan daftar personal access token
+func (tc *TokenController) List(ctx *fiber.Ctx) error {
+	response := tc.Service.List(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// Delete menangani pencabutan personal access token
+func (tc *TokenController) Delete(ctx *fiber.Ctx) error {
+	response := tc.Service.Delete(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -0,0 +1,236 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/middleware"
+	"github.com/achyar10/go-auth/src/utility"
+)
+
+// lastUsedPrecision last_used_at hanya diperbarui jika sudah lewat selang ini, agar tidak menulis ke DB setiap request
+const lastUsedPrecision = time.Minute
+
+// ErrAPIKeyInvalid token tidak dikenal, kedaluwarsa, atau pemiliknya tidak aktif
+var ErrAPIKeyInvalid = errors.New("invalid or expired personal access token")
+
+// TokenService interface
+type TokenService interface {
+	Create(ctx *fiber.Ctx) utility.APIResponse
+	List(ctx *fiber.Ctx) utility.APIResponse
+	Delete(ctx *fiber.Ctx) utility.APIResponse
+	Authenticate(token string) (*middleware.APIKeyIdentity, error)
+}
+
+// TokenServiceImpl struct
+type TokenServiceImpl struct {
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+// Konstruktor untuk TokenService
+func NewTokenService(db *gorm.DB) TokenService {
+	return &TokenServiceImpl{
+		DB:       db,
+		Validate: validator.New(),
+	}
+}
+
+// maxTokensPerUser batas jumlah personal access token per user
+func maxTokensPerUser() int64 {
+	return int64(helper.GetEnvInt("API_KEY_MAX_PER_USER", 20))
+}
+
+// Implementasi Create (membuat personal access token; token mentah hanya ditampilkan sekali)
+func (t *TokenServiceImpl) Create(ctx *fiber.Ctx) utility.APIResponse {
+	var dto CreateTokenDTO
+
+	// Token tidak boleh membuat token lain, agar token read-only / token client OAuth tidak bisa menaikkan aksesnya sendiri
+	if delegatedToken(ctx) {
+		return utility.ErrorResponse(http.StatusForbidden, "Personal access tokens and OAuth access tokens cannot manage tokens", nil)
+	}
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi DTO
+	if err := t.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	userID, response := t.tokenOwner(ctx)
+	if response != nil {
+		return *response
+	}
+
+	var count int64
+	if err := t.DB.Model(&PersonalAccessToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create token", []string{err.Error()})
+	}
+	if count >= maxTokensPerUser() {
+		return utility.ErrorResponse(http.StatusConflict, "Token limit reached, delete an unused token first", nil)
+	}
+
+	rawToken, prefix, err := generateAPIKey()
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create token", []string{err.Error()})
+	}
+
+	token := PersonalAccessToken{
+		UserId:    userID,
+		Name:      dto.Name,
+		Prefix:    prefix,
+		TokenHash: helper.HashToken(rawToken),
+		Scopes:    uniqueScopes(dto.Scopes),
+	}
+	if dto.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *dto.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := t.DB.Create(&token).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create token", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusCreated, "Token created, copy it now because it will not be shown again", PersonalAccessTokenResponse{
+		PersonalAccessToken: token,
+		Token:               rawToken,
+	})
+}
+
+// Implementasi List (daftar personal access token milik user yang sedang login / service account-nya)
+func (t *TokenServiceImpl) List(ctx *fiber.Ctx) utility.APIResponse {
+	var tokens []PersonalAccessToken
+	userID, response := t.tokenOwner(ctx)
+	if response != nil {
+		return *response
+	}
+
+	if err := t.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve tokens", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", tokens)
+}
+
+// Implementasi Delete (mencabut personal access token milik user)
+func (t *TokenServiceImpl) Delete(ctx *fiber.Ctx) utility.APIResponse {
+	if delegatedToken(ctx) {
+		return utility.ErrorResponse(http.StatusForbidden, "Personal access tokens and OAuth access tokens cannot manage tokens", nil)
+	}
+
+	userID, response := t.tokenOwner(ctx)
+	if response != nil {
+		return *response
+	}
+
+	result := t.DB.Where("id = ? AND user_id = ?", ctx.Params("tokenId"), userID).Delete(&PersonalAccessToken{})
+	if result.Error != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to delete token", []string{result.Error.Error()})
+	}
+	if result.RowsAffected == 0 {
+		return utility.ErrorResponse(http.StatusNotFound, "Token not found", nil)
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Token deleted successfully", nil)
+}
+
+// Authenticate memverifikasi personal access token untuk middleware.AuthMiddleware
+func (t *TokenServiceImpl) Authenticate(rawToken string) (*middleware.APIKeyIdentity, error) {
+	var token PersonalAccessToken
+	if err := t.DB.Where("token_hash = ?", helper.HashToken(rawToken)).First(&token).Error; err != nil {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var owner User
+	if err := t.DB.Where("id = ? AND is_active = ?", token.UserId, true).First(&owner).Error; err != nil {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedPrecision {
+		t.DB.Model(&PersonalAccessToken{}).Where("id = ?", token.Id).Update("last_used_at", now)
+	}
+
+	fullname := ""
+	if owner.Fullname != nil {
+		fullname = *owner.Fullname
+	}
+	return &middleware.APIKeyIdentity{
+		TokenId:  token.Id,
+		UserId:   owner.Id,
+		Username: owner.Username,
+		Fullname: fullname,
+		Role:     string(owner.Role),
+		Scope:    strings.Join(token.Scopes, " "),
+	}, nil
+}
+
+// tokenOwner menentukan pemilik token: user yang sedang login (/user/me/tokens) atau service account
+// (/user/:id/tokens) yang hanya bisa dikelola oleh pemiliknya atau admin
+func (t *TokenServiceImpl) tokenOwner(ctx *fiber.Ctx) (int64, *utility.APIResponse) {
+	userID := int64(ctx.Locals("user_id").(float64))
+	if ctx.Params("id") == "" {
+		return userID, nil
+	}
+
+	var account User
+	if err := t.DB.Select("id", "kind", "owner_id").First(&account, ctx.Params("id")).Error; err != nil || !account.IsServiceAccount() {
+		response := utility.ErrorResponse(http.StatusNotFound, "Service account not found", nil)
+		return 0, &response
+	}
+	if ctx.Locals("role") != string(ADMIN) && (account.OwnerId == nil || *account.OwnerId != userID) {
+		response := utility.ErrorResponse(http.StatusForbidden, "Only the owner or an admin can manage this service account", nil)
+		return 0, &response
+	}
+	return account.Id, nil
+}
+
+// delegatedToken true jika request memakai personal access token atau access token yang diterbitkan untuk client OAuth
+func delegatedToken(ctx *fiber.Ctx) bool {
+	clientID, _ := ctx.Locals("client_id").(string)
+	return ctx.Locals("token_type") == "api_key" || clientID != ""
+}
+
+// generateAPIKey membuat token dengan format pat_<id>_<secret>; bagian pat_<id> disimpan sebagai prefix
+func generateAPIKey() (string, string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := helper.PersonalAccessTokenPrefix + hex.EncodeToString(id)
+	return prefix + "_" + secret, prefix, nil
+}
+
+// uniqueScopes membuang scope duplikat dengan urutan tetap
+func uniqueScopes(scopes []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
@@ -49,3 +49,27 @@ func (uc *UserController) ResetPasswordUser(ctx *fiber.Ctx) error {
 	response := uc.Service.ResetPassword(ctx)
 	return ctx.Status(response.Status).JSON(response)
 }
+
+// ChangePasswordUser menangani penggantian kata sandi oleh pengguna yang sedang login
+func (uc *UserController) ChangePasswordUser(ctx *fiber.Ctx) error {
+	response := uc.Service.ChangePassword(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// ProfileUser menangani pengambilan profil pengguna yang sedang login
+func (uc *UserController) ProfileUser(ctx *fiber.Ctx) error {
+	response := uc.Service.Profile(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// LockStatusUser menangani pengambilan status lockout login pengguna
+func (uc *UserController) LockStatusUser(ctx *fiber.Ctx) error {
+	response := uc.Service.LockStatus(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
+
+// UnlockUser menangani pembukaan kunci akun pengguna
+func (uc *UserController) UnlockUser(ctx *fiber.Ctx) error {
+	response := uc.Service.Unlock(ctx)
+	return ctx.Status(response.Status).JSON(response)
+}
@@ -2,10 +2,23 @@ package user
 
 type CreateUserDTO struct {
 	Username string  `json:"username" validate:"required,min=3,max=100"`
-	Password string  `json:"password" validate:"required,min=8"`
+	Password string  `json:"password" validate:"required_unless=Kind service,excluded_if=Kind service"` // Aturan lain lewat helper.ValidatePassword
 	Fullname *string `json:"fullname"`
+	Email    *string `json:"email" validate:"omitempty,email,max=255"`
 	Role     Role    `json:"role" validate:"oneof=admin user"`
 	IsActive *bool   `json:"is_active"`
+	Kind     string  `json:"kind" validate:"omitempty,oneof=human service"`
+	OwnerId  *int64  `json:"owner_id"` // Hanya untuk service account, default user yang sedang login
+}
+
+// UpdateUserDTO hanya memuat field yang boleh diubah lewat PUT /user/:id
+type UpdateUserDTO struct {
+	Fullname   *string `json:"fullname"`
+	Email      *string `json:"email" validate:"omitempty,email,max=255"` // String kosong menghapus email
+	Role       *Role   `json:"role" validate:"omitempty,oneof=admin user"`
+	IsActive   *bool   `json:"is_active"`
+	AuthSource *string `json:"auth_source" validate:"omitempty,oneof=local ldap"`
+	OwnerId    *int64  `json:"owner_id"` // Hanya untuk service account
 }
 
 type ListUserQueryDTO struct {
@@ -16,6 +29,16 @@ type ListUserQueryDTO struct {
 }
 
 type ResetPasswordUserDTO struct {
-	OldPassword string `json:"old_password" validate:"required,min=8"`
-	NewPassword string `json:"new_password" validate:"required,min=8"`
+	NewPassword string `json:"new_password" validate:"required"` // Aturan lain lewat helper.ValidatePassword
+}
+
+type ChangePasswordDTO struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"` // Aturan lain lewat helper.ValidatePassword
+}
+
+type CreateTokenDTO struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read write"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
 }
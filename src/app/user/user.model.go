@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/helper"
 )
 
 type Role string
@@ -13,15 +15,122 @@ const (
 	USER  Role = "user"
 )
 
+// Jenis akun: manusia, atau service account (robot) yang hanya bisa memakai API key / client credentials
+const (
+	KindHuman   = "human"
+	KindService = "service"
+)
+
+// Sumber kredensial user: password lokal atau direktori LDAP / Active Directory
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+)
+
 type User struct {
-	Id        int64     `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"type:varchar(100);not null" json:"username"`
-	Password  *string   `gorm:"type:varchar(255);null" json:"-"`
-	Fullname  *string   `gorm:"type:varchar(255);null" json:"fullname"`
-	Role      Role      `gorm:"type:enum('admin', 'user');default:'user'" json:"role"`
-	IsActive  bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	Id              int64      `gorm:"primaryKey" json:"id"`
+	Username        string     `gorm:"type:varchar(100);not null" json:"username"`
+	Password        *string    `gorm:"type:varchar(255);null" json:"-"` // argon2id / bcrypt, atau format lama hasil impor (lihat helper.legacyPasswordHashers)
+	Fullname        *string    `gorm:"type:varchar(255);null" json:"fullname"`
+	Email           *string    `gorm:"type:varchar(255);uniqueIndex;null" json:"email"`
+	EmailVerifiedAt *time.Time `gorm:"type:datetime;null" json:"email_verified_at"`
+	Role            Role       `gorm:"type:enum('admin', 'user');default:'user'" json:"role"`
+	AuthSource      string     `gorm:"type:varchar(20);not null;default:'local'" json:"auth_source"`
+	Kind            string     `gorm:"type:varchar(20);not null;default:'human';index" json:"kind"`
+	OwnerId         *int64     `gorm:"index;null" json:"owner_id"`              // Pemilik service account (user manusia)
+	Groups          []string   `gorm:"type:text;serializer:json" json:"groups"` // Disinkronkan dari direktori saat login
+	IsActive        bool       `gorm:"default:true" json:"is_active"`
+	TotpSecret      *string    `gorm:"type:varchar(255);null" json:"-"` // Terenkripsi dengan helper.Encrypt
+	TotpLastStep    *int64     `gorm:"null" json:"-"`                   // Time step (unix / 30 detik) kode TOTP terakhir yang diterima, kode lama tidak bisa dipakai ulang
+	MfaEnabled      bool       `gorm:"default:false" json:"mfa_enabled"`
+	FailedLogins    int        `gorm:"not null;default:0" json:"failed_logins"`
+	LastFailedAt    *time.Time `gorm:"type:datetime;null" json:"last_failed_at"`
+	LockedUntil     *time.Time `gorm:"type:datetime;null" json:"locked_until"`
+	CreatedAt       time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// RecoveryCode adalah kode cadangan MFA sekali pakai. CodeHash berisi SHA-256 kode, bukan bcrypt seperti password:
+// kode dibuat acak (sekitar 79 bit, lihat helper.GenerateRecoveryCodes) sehingga tidak butuh key stretching,
+// dan hash deterministik bisa dicari lewat index lalu ditandai terpakai dengan satu update bersyarat
+// (bcrypt mengharuskan membandingkan setiap kode user satu per satu).
+type RecoveryCode struct {
+	Id        int64      `gorm:"primaryKey" json:"id"`
+	UserId    int64      `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(255);not null;index" json:"-"`
+	UsedAt    *time.Time `gorm:"type:datetime;null" json:"used_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// WebAuthnCredential adalah passkey / security key (WebAuthn) yang terdaftar untuk user
+type WebAuthnCredential struct {
+	Id              int64      `gorm:"primaryKey" json:"id"`
+	UserId          int64      `gorm:"not null;index" json:"user_id"`
+	Name            string     `gorm:"type:varchar(100)" json:"name"`
+	CredentialId    []byte     `gorm:"type:varbinary(255);not null;uniqueIndex" json:"-"`
+	PublicKey       []byte     `gorm:"type:blob;not null" json:"-"`
+	AttestationType string     `gorm:"type:varchar(50)" json:"attestation_type"`
+	AAGUID          []byte     `gorm:"type:varbinary(16)" json:"-"`
+	SignCount       uint32     `gorm:"not null;default:0" json:"sign_count"`
+	Transports      string     `gorm:"type:varchar(255)" json:"transports"` // Dipisah koma, misal "usb,nfc"
+	BackupEligible  bool       `gorm:"default:false" json:"backup_eligible"`
+	BackupState     bool       `gorm:"default:false" json:"backup_state"`
+	LastUsedAt      *time.Time `gorm:"type:datetime;null" json:"last_used_at"`
+	CreatedAt       time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName nama tabel kredensial WebAuthn
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// UserIdentity menghubungkan user dengan akun di identity provider eksternal (login federasi OIDC)
+type UserIdentity struct {
+	Id          int64      `gorm:"primaryKey" json:"id"`
+	UserId      int64      `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	Email       *string    `gorm:"type:varchar(255);null" json:"email"`
+	LastLoginAt *time.Time `gorm:"type:datetime;null" json:"last_login_at"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName nama tabel identitas federasi
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// PersonalAccessToken adalah API key milik user untuk script / CI, hanya hash secret-nya yang disimpan
+type PersonalAccessToken struct {
+	Id         int64      `gorm:"primaryKey" json:"id"`
+	UserId     int64      `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(20);not null;index" json:"prefix"` // Bagian awal token untuk identifikasi, misal "pat_AbCd1234"
+	TokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"type:text;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `gorm:"type:datetime;null" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"type:datetime;null" json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// PersonalAccessTokenResponse dikembalikan sekali saat token dibuat, berisi token mentah
+type PersonalAccessTokenResponse struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// LockStatusResponse status lockout login user
+type LockStatusResponse struct {
+	Locked       bool       `json:"locked"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	FailedLogins int        `json:"failed_logins"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+}
+
+// ProfileResponse data profil user yang sedang login
+type ProfileResponse struct {
+	User
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
 }
 
 // BeforeCreate memastikan default Role dan timestamp
@@ -29,13 +138,49 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	if u.Role == "" {
 		u.Role = USER // Default role ke "user"
 	}
+	if u.AuthSource == "" {
+		u.AuthSource = AuthSourceLocal
+	}
+	if u.Kind == "" {
+		u.Kind = KindHuman
+	}
 	u.CreatedAt = time.Now() // Set waktu sekarang untuk created_at
 	u.UpdatedAt = time.Now() // Set waktu sekarang untuk updated_at
 	return nil
 }
 
+// IsServiceAccount mengecek apakah user adalah service account
+func (u User) IsServiceAccount() bool {
+	return u.Kind == KindService
+}
+
+// PersonalInfo data user yang tidak boleh dipakai sebagai bagian dari password
+func (u User) PersonalInfo() []string {
+	info := []string{u.Username}
+	if u.Fullname != nil {
+		info = append(info, *u.Fullname)
+	}
+	if u.Email != nil {
+		info = append(info, *u.Email)
+	}
+	return info
+}
+
 // BeforeUpdate untuk memperbarui updated_at setiap update
 func (u *User) BeforeUpdate(tx *gorm.DB) (err error) {
 	u.UpdatedAt = time.Now()
 	return nil
 }
+
+// BeforeSave menormalisasi email (huruf kecil, tanpa spasi) sebelum disimpan
+func (u *User) BeforeSave(tx *gorm.DB) (err error) {
+	if u.Email != nil {
+		email := helper.NormalizeEmail(*u.Email)
+		if email == "" {
+			u.Email = nil
+		} else {
+			u.Email = &email
+		}
+	}
+	return nil
+}
@@ -11,17 +11,37 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	// Inisialisasi service dan controller
 	userService := NewUserService(db)
 	userController := NewUserController(userService)
+	tokenService := NewTokenService(db)
+	tokenController := NewTokenController(tokenService)
+
+	// AuthMiddleware menerima personal access token lewat service ini
+	middleware.APIKeyAuthenticator = tokenService.Authenticate
 
 	// Grouping endpoint users
 	userRoutes := app.Group("/user")
 
-	// Middleware
-	userRoutes.Use(middleware.AuthMiddleware)
+	// Middleware; aksi yang mengubah akun / kredensial ditolak saat impersonasi
+	userRoutes.Use(middleware.AuthMiddleware, middleware.RequireUser)
 
-	userRoutes.Post("/", userController.CreateUser)
+	userRoutes.Post("/", middleware.ForbidImpersonation, userController.CreateUser)
 	userRoutes.Get("/", userController.ListUser)
+	userRoutes.Get("/me", userController.ProfileUser)
+	userRoutes.Patch("/me/password",
+		middleware.ForbidImpersonation,
+		middleware.RateLimit("change_password_user", "5/15m", middleware.RateLimitByClient),
+		userController.ChangePasswordUser)
+	userRoutes.Post("/me/tokens", middleware.ForbidImpersonation, tokenController.Create)
+	userRoutes.Get("/me/tokens", tokenController.List)
+	userRoutes.Delete("/me/tokens/:tokenId", middleware.ForbidImpersonation, tokenController.Delete)
 	userRoutes.Get("/:id", userController.DetailUser)
-	userRoutes.Put("/:id", userController.UpdateUser)
-	userRoutes.Delete("/:id", userController.DeleteUser)
-	userRoutes.Patch("/:id/rpw", userController.ResetPasswordUser)
+	userRoutes.Put("/:id", middleware.ForbidImpersonation, userController.UpdateUser)
+	userRoutes.Delete("/:id", middleware.ForbidImpersonation, userController.DeleteUser)
+	userRoutes.Patch("/:id/rpw", middleware.RoleMiddleware("admin"), middleware.ForbidImpersonation, userController.ResetPasswordUser)
+	userRoutes.Get("/:id/lock", middleware.RoleMiddleware("admin"), userController.LockStatusUser)
+	userRoutes.Post("/:id/unlock", middleware.RoleMiddleware("admin"), middleware.ForbidImpersonation, userController.UnlockUser)
+
+	// API key service account (dikelola oleh pemilik atau admin)
+	userRoutes.Post("/:id/tokens", middleware.ForbidImpersonation, tokenController.Create)
+	userRoutes.Get("/:id/tokens", tokenController.List)
+	userRoutes.Delete("/:id/tokens/:tokenId", middleware.ForbidImpersonation, tokenController.Delete)
 }
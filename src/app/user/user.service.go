@@ -1,7 +1,9 @@
 package user
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/achyar10/go-auth/src/helper"
 	"github.com/achyar10/go-auth/src/utility"
@@ -18,6 +20,10 @@ type UserService interface {
 	Update(ctx *fiber.Ctx) utility.APIResponse
 	Delete(ctx *fiber.Ctx) utility.APIResponse
 	ResetPassword(ctx *fiber.Ctx) utility.APIResponse
+	ChangePassword(ctx *fiber.Ctx) utility.APIResponse
+	Profile(ctx *fiber.Ctx) utility.APIResponse
+	LockStatus(ctx *fiber.Ctx) utility.APIResponse
+	Unlock(ctx *fiber.Ctx) utility.APIResponse
 }
 
 // UserServiceImpl adalah implementasi dari UserService
@@ -44,8 +50,15 @@ func (u *UserServiceImpl) List(ctx *fiber.Ctx) utility.APIResponse {
 	// Tentukan field yang bisa dicari dalam tabel `users`
 	searchableFields := []string{"username", "fullname", "role"}
 
+	// User manusia dan service account ditampilkan terpisah, default user manusia (?kind=service untuk service account)
+	kind := query.Filters["kind"]
+	if kind != KindService {
+		kind = KindHuman
+	}
+	delete(query.Filters, "kind")
+
 	// Gunakan helper ApplyFiltersAndPagination
-	paginatedResult := helper.ApplyFiltersAndPagination(u.DB, &users, query, searchableFields)
+	paginatedResult := helper.ApplyFiltersAndPagination(u.DB.Where("kind = ?", kind).Session(&gorm.Session{}), &users, query, searchableFields)
 
 	// Generate metadata
 	metadata := helper.GenerateMetadata(query, paginatedResult.TotalCount, paginatedResult.PageCount)
@@ -79,16 +92,52 @@ func (u *UserServiceImpl) Create(ctx *fiber.Ctx) utility.APIResponse {
 		dto.IsActive = &defaultIsActive
 	}
 
-	// Buat user baru
-	// Hash password sebelum disimpan
-	hashedPassword := helper.HashPassword(dto.Password)
+	// Email harus unik
+	if dto.Email != nil {
+		taken, err := EmailTaken(u.DB, *dto.Email, 0)
+		if err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+		}
+		if taken {
+			return utility.ErrorResponse(http.StatusConflict, "Email is already registered", nil)
+		}
+	}
 
+	// Buat user baru
 	user := User{
 		Username: dto.Username,
-		Password: &hashedPassword,
 		Fullname: dto.Fullname,
+		Email:    dto.Email,
 		Role:     dto.Role,
 		IsActive: *dto.IsActive,
+		Kind:     KindHuman,
+	}
+
+	if dto.Kind == KindService {
+		// Service account tidak punya password dan selalu dimiliki user manusia, default yang membuatnya
+		ownerID := int64(ctx.Locals("user_id").(float64))
+		if dto.OwnerId != nil {
+			ownerID = *dto.OwnerId
+		}
+		if response := u.checkOwner(ownerID); response != nil {
+			return *response
+		}
+		user.Kind = KindService
+		user.OwnerId = &ownerID
+	} else {
+		if dto.OwnerId != nil {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"owner_id hanya untuk service account"})
+		}
+
+		// Validasi password terhadap password policy, lalu hash sebelum disimpan
+		if errs := helper.ValidatePassword(dto.Password, user.PersonalInfo()...); len(errs) > 0 {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", errs)
+		}
+		hashedPassword, err := helper.HashPassword(dto.Password)
+		if err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to create user", []string{err.Error()})
+		}
+		user.Password = &hashedPassword
 	}
 
 	// Simpan ke database
@@ -115,6 +164,30 @@ func (u *UserServiceImpl) Detail(ctx *fiber.Ctx) utility.APIResponse {
 	return utility.SuccessResponse(http.StatusOK, "OK", user)
 }
 
+// Implementasi Profile (data user yang sedang login)
+func (u *UserServiceImpl) Profile(ctx *fiber.Ctx) utility.APIResponse {
+	var profile ProfileResponse
+	userID := int64(ctx.Locals("user_id").(float64))
+
+	if err := u.DB.First(&profile.User, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	// Sisa kode pemulihan MFA yang belum terpakai
+	if profile.MfaEnabled {
+		if err := u.DB.Model(&RecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&profile.RecoveryCodesRemaining).Error; err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+		}
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", profile)
+}
+
 // Implementasi UpdateUser
 func (u *UserServiceImpl) Update(ctx *fiber.Ctx) utility.APIResponse {
 	id := ctx.Params("id")
@@ -128,14 +201,85 @@ func (u *UserServiceImpl) Update(ctx *fiber.Ctx) utility.APIResponse {
 		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
 	}
 
+	var dto UpdateUserDTO
+
 	// Parsing request body
-	if err := ctx.BodyParser(&user); err != nil {
+	if err := ctx.BodyParser(&dto); err != nil {
 		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
 	}
 
+	// Validasi DTO
+	if err := u.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	// Hanya kolom yang dikirim di body yang diperbarui
+	var columns []string
+	if dto.Fullname != nil {
+		user.Fullname = dto.Fullname
+		columns = append(columns, "fullname")
+	}
+	if dto.Role != nil {
+		user.Role = *dto.Role
+		columns = append(columns, "role")
+	}
+	if dto.IsActive != nil {
+		user.IsActive = *dto.IsActive
+		columns = append(columns, "is_active")
+	}
+	if dto.AuthSource != nil {
+		user.AuthSource = *dto.AuthSource
+		columns = append(columns, "auth_source")
+	}
+
+	// Pemilik hanya bisa dipindahkan untuk service account
+	if dto.OwnerId != nil {
+		if !user.IsServiceAccount() {
+			return utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"owner_id hanya untuk service account"})
+		}
+		if user.OwnerId == nil || *user.OwnerId != *dto.OwnerId {
+			if response := u.checkOwner(*dto.OwnerId); response != nil {
+				return *response
+			}
+		}
+		user.OwnerId = dto.OwnerId
+		columns = append(columns, "owner_id")
+	}
+
+	// Email harus unik, dan status verifikasi direset jika email diganti
+	if dto.Email != nil {
+		var email *string
+		if *dto.Email != "" {
+			email = dto.Email
+		}
+		if emailChanged(user.Email, email) {
+			// Email adalah tujuan link reset password / magic link, hanya admin atau pemilik akun yang boleh menggantinya
+			role, _ := ctx.Locals("role").(string)
+			userID, _ := ctx.Locals("user_id").(float64)
+			if role != string(ADMIN) && int64(userID) != user.Id {
+				return utility.ErrorResponse(http.StatusForbidden, "Only an admin or the account owner can change the email", nil)
+			}
+			if email != nil {
+				taken, err := EmailTaken(u.DB, *email, user.Id)
+				if err != nil {
+					return utility.ErrorResponse(http.StatusInternalServerError, "Failed to update user", []string{err.Error()})
+				}
+				if taken {
+					return utility.ErrorResponse(http.StatusConflict, "Email is already registered", nil)
+				}
+			}
+			user.Email = email
+			user.EmailVerifiedAt = nil
+			columns = append(columns, "email", "email_verified_at")
+		}
+	}
+
 	// Update user di database
-	if err := u.DB.Save(&user).Error; err != nil {
-		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to update user", []string{err.Error()})
+	if len(columns) > 0 {
+		columns = append(columns, "updated_at")
+		if err := u.DB.Model(&user).Select(columns).Updates(&user).Error; err != nil {
+			return utility.ErrorResponse(http.StatusInternalServerError, "Failed to update user", []string{err.Error()})
+		}
 	}
 
 	return utility.SuccessResponse(http.StatusOK, "User updated successfully", user)
@@ -162,10 +306,16 @@ func (u *UserServiceImpl) Delete(ctx *fiber.Ctx) utility.APIResponse {
 	return utility.SuccessResponse(http.StatusOK, "User deleted successfully", nil)
 }
 
+// Implementasi ResetPassword (admin mengganti password user, tanpa password lama)
 func (u *UserServiceImpl) ResetPassword(ctx *fiber.Ctx) utility.APIResponse {
 	id := ctx.Params("id")
 	var dto ResetPasswordUserDTO
 
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
 	// Validasi input DTO
 	if err := u.Validate.Struct(&dto); err != nil {
 		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
@@ -173,20 +323,155 @@ func (u *UserServiceImpl) ResetPassword(ctx *fiber.Ctx) utility.APIResponse {
 
 	// Cek apakah user ada
 	var user User
-	if err := u.DB.Select("id").First(&user, id).Error; err != nil {
+	if err := u.DB.Select("id", "kind", "username", "fullname", "email").First(&user, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
 		}
 		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
 	}
+	if user.IsServiceAccount() {
+		return utility.ErrorResponse(http.StatusBadRequest, "Service accounts do not have a password", nil)
+	}
+
+	// Validasi password baru terhadap password policy
+	if errs := helper.ValidatePassword(dto.NewPassword, user.PersonalInfo()...); len(errs) > 0 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", errs)
+	}
+
+	hashedPassword, err := helper.HashPassword(dto.NewPassword)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to reset password", []string{err.Error()})
+	}
 
-	// Update password dengan hashing langsung di query
 	if err := u.DB.Model(&User{}).Where("id = ?", id).
 		Updates(map[string]interface{}{
-			"password": helper.HashPassword(dto.NewPassword),
+			"password": hashedPassword,
 		}).Error; err != nil {
 		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to reset password", []string{err.Error()})
 	}
 
 	return utility.SuccessResponse(http.StatusOK, "Password reset successfully", nil)
 }
+
+// Implementasi ChangePassword (user mengganti password sendiri, wajib dengan password lama)
+func (u *UserServiceImpl) ChangePassword(ctx *fiber.Ctx) utility.APIResponse {
+	userID := int64(ctx.Locals("user_id").(float64))
+	var dto ChangePasswordDTO
+
+	// Parsing body request
+	if err := ctx.BodyParser(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Invalid request body", []string{err.Error()})
+	}
+
+	// Validasi input DTO
+	if err := u.Validate.Struct(&dto); err != nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", helper.GetValidationErrors(err))
+	}
+
+	var user User
+	if err := u.DB.Select("id", "kind", "auth_source", "username", "fullname", "email", "password").First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	// Password user LDAP dikelola di direktori; service account tidak punya password
+	if user.IsServiceAccount() || user.AuthSource != AuthSourceLocal || user.Password == nil {
+		return utility.ErrorResponse(http.StatusBadRequest, "Password cannot be changed for this account", nil)
+	}
+
+	if !helper.CheckPasswordHash(dto.OldPassword, *user.Password) {
+		return utility.ErrorResponse(http.StatusBadRequest, "Old password is incorrect", nil)
+	}
+
+	// Validasi password baru terhadap password policy
+	if errs := helper.ValidatePassword(dto.NewPassword, user.PersonalInfo()...); len(errs) > 0 {
+		return utility.ErrorResponse(http.StatusBadRequest, "Validation error", errs)
+	}
+
+	hashedPassword, err := helper.HashPassword(dto.NewPassword)
+	if err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to change password", []string{err.Error()})
+	}
+
+	if err := u.DB.Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"password": hashedPassword,
+		}).Error; err != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to change password", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "Password changed successfully", nil)
+}
+
+// Implementasi LockStatus (status lockout akibat login gagal)
+func (u *UserServiceImpl) LockStatus(ctx *fiber.Ctx) utility.APIResponse {
+	var user User
+	if err := u.DB.Select("id", "failed_logins", "last_failed_at", "locked_until").First(&user, ctx.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+		}
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+	}
+
+	return utility.SuccessResponse(http.StatusOK, "OK", LockStatusResponse{
+		Locked:       user.LockedUntil != nil && time.Now().Before(*user.LockedUntil),
+		LockedUntil:  user.LockedUntil,
+		FailedLogins: user.FailedLogins,
+		LastFailedAt: user.LastFailedAt,
+	})
+}
+
+// Implementasi Unlock (admin membuka kunci akun dan mereset penghitung login gagal)
+func (u *UserServiceImpl) Unlock(ctx *fiber.Ctx) utility.APIResponse {
+	result := u.DB.Model(&User{}).Where("id = ?", ctx.Params("id")).
+		Updates(map[string]interface{}{"failed_logins": 0, "last_failed_at": nil, "locked_until": nil})
+	if result.Error != nil {
+		return utility.ErrorResponse(http.StatusInternalServerError, "Failed to unlock user", []string{result.Error.Error()})
+	}
+	if result.RowsAffected == 0 {
+		return utility.ErrorResponse(http.StatusNotFound, "User not found", nil)
+	}
+
+	log.Printf("Akun user %s dibuka kuncinya oleh admin %v", ctx.Params("id"), ctx.Locals("username"))
+	return utility.SuccessResponse(http.StatusOK, "User unlocked successfully", nil)
+}
+
+// checkOwner memastikan pemilik service account adalah user manusia yang ada
+func (u *UserServiceImpl) checkOwner(ownerID int64) *utility.APIResponse {
+	var owner User
+	if err := u.DB.Select("id", "kind").First(&owner, ownerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"owner_id tidak ditemukan"})
+			return &response
+		}
+		response := utility.ErrorResponse(http.StatusInternalServerError, "Failed to retrieve user", []string{err.Error()})
+		return &response
+	}
+	if owner.IsServiceAccount() {
+		response := utility.ErrorResponse(http.StatusBadRequest, "Validation error", []string{"owner_id harus user manusia, bukan service account"})
+		return &response
+	}
+	return nil
+}
+
+// EmailTaken mengecek apakah email (setelah dinormalisasi) sudah dipakai user lain
+func EmailTaken(db *gorm.DB, email string, excludeID int64) (bool, error) {
+	var count int64
+	err := db.Model(&User{}).
+		Where("email = ? AND id <> ?", helper.NormalizeEmail(email), excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// emailChanged membandingkan email lama dan baru setelah dinormalisasi
+func emailChanged(previous, current *string) bool {
+	normalize := func(email *string) string {
+		if email == nil {
+			return ""
+		}
+		return helper.NormalizeEmail(*email)
+	}
+	return normalize(previous) != normalize(current)
+}
@@ -0,0 +1,210 @@
+package user
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+// updateTestApp route PUT /user/:id dengan user login (user_id dan role) yang ditentukan test
+func updateTestApp(db *gorm.DB, userID int64, role string) *fiber.App {
+	app := fiber.New()
+	controller := NewUserController(NewUserService(db))
+	app.Put("/user/:id", func(ctx *fiber.Ctx) error {
+		ctx.Locals("user_id", float64(userID))
+		ctx.Locals("role", role)
+		return ctx.Next()
+	}, controller.UpdateUser)
+	return app
+}
+
+func putUser(t *testing.T, app *fiber.App, path string, body string) int {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+func TestUpdateIgnoresFieldsOutsideDTO(t *testing.T) {
+	db := testutil.NewDB(t)
+	secret := "JBSWY3DPEHPK3PXP"
+	lockedUntil := time.Now().Add(time.Hour)
+	target := User{Username: "budi", Kind: KindHuman, MfaEnabled: true, TotpSecret: &secret,
+		FailedLogins: 5, LockedUntil: &lockedUntil, Groups: []string{"staff"}}
+	if err := db.Create(&target).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	app := updateTestApp(db, target.Id, "admin")
+	body := `{"fullname": "Budi Santoso", "mfa_enabled": false, "totp_secret": null, "failed_logins": 0,
+		"locked_until": null, "groups": ["admin"], "kind": "service", "password": "x"}`
+	if got := putUser(t, app, "/user/1", body); got != http.StatusOK {
+		t.Fatalf("status = %d, want %d", got, http.StatusOK)
+	}
+
+	var stored User
+	if err := db.First(&stored, target.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Fullname == nil || *stored.Fullname != "Budi Santoso" {
+		t.Errorf("fullname = %v, want Budi Santoso", stored.Fullname)
+	}
+	if !stored.MfaEnabled || stored.TotpSecret == nil {
+		t.Error("MFA state changed through PUT /user/:id")
+	}
+	if stored.FailedLogins != 5 || stored.LockedUntil == nil {
+		t.Error("lockout state changed through PUT /user/:id")
+	}
+	if len(stored.Groups) != 1 || stored.Groups[0] != "staff" {
+		t.Errorf("groups = %v, want [staff]", stored.Groups)
+	}
+	if stored.Kind != KindHuman || stored.Password != nil {
+		t.Error("kind or password changed through PUT /user/:id")
+	}
+}
+
+func TestUpdateValidatesAuthSource(t *testing.T) {
+	db := testutil.NewDB(t)
+	target := User{Username: "budi", Kind: KindHuman}
+	if err := db.Create(&target).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	app := updateTestApp(db, target.Id, "admin")
+	if got := putUser(t, app, "/user/1", `{"auth_source": "kerberos"}`); got != http.StatusBadRequest {
+		t.Errorf("unknown auth_source: status = %d, want %d", got, http.StatusBadRequest)
+	}
+	if got := putUser(t, app, "/user/1", `{"auth_source": "ldap"}`); got != http.StatusOK {
+		t.Errorf("ldap auth_source: status = %d, want %d", got, http.StatusOK)
+	}
+
+	var stored User
+	if err := db.First(&stored, target.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.AuthSource != AuthSourceLDAP {
+		t.Errorf("auth_source = %q, want %q", stored.AuthSource, AuthSourceLDAP)
+	}
+}
+
+func TestUpdateEmailRequiresAdminOrSelf(t *testing.T) {
+	db := testutil.NewDB(t)
+	verifiedAt := time.Now()
+	email := "budi@example.com"
+	target := User{Username: "budi", Kind: KindHuman, Email: &email, EmailVerifiedAt: &verifiedAt}
+	other := User{Username: "siti", Kind: KindHuman}
+	if err := db.Create(&target).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"email": "attacker@example.com"}`
+	if got := putUser(t, updateTestApp(db, other.Id, "user"), "/user/1", body); got != http.StatusForbidden {
+		t.Errorf("other user: status = %d, want %d", got, http.StatusForbidden)
+	}
+	if got := putUser(t, updateTestApp(db, target.Id, "user"), "/user/1", `{"email": "budi@example.com"}`); got != http.StatusOK {
+		t.Errorf("self, same email: status = %d, want %d", got, http.StatusOK)
+	}
+
+	var stored User
+	if err := db.First(&stored, target.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.EmailVerifiedAt == nil {
+		t.Error("email_verified_at reset although the email did not change")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		userID int64
+		role   string
+		email  string
+	}{
+		{"self", target.Id, "user", "budi.baru@example.com"},
+		{"admin", other.Id, "admin", "budi.lagi@example.com"},
+	} {
+		body := `{"email": "` + tc.email + `"}`
+		if got := putUser(t, updateTestApp(db, tc.userID, tc.role), "/user/1", body); got != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tc.name, got, http.StatusOK)
+		}
+		stored = User{}
+		if err := db.First(&stored, target.Id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.Email == nil || *stored.Email != tc.email {
+			t.Errorf("%s: email = %v, want %s", tc.name, stored.Email, tc.email)
+		}
+		if stored.EmailVerifiedAt != nil {
+			t.Errorf("%s: email_verified_at not reset after the email changed", tc.name)
+		}
+		if err := db.Model(&stored).Update("email_verified_at", time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", helper.PasswordHashBcrypt)
+	t.Setenv("BCRYPT_COST", "4")
+	db := testutil.NewDB(t)
+
+	hashedPassword, err := helper.HashPassword("Old-passphrase-91")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := User{Username: "budi", Kind: KindHuman, Password: &hashedPassword}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	controller := NewUserController(NewUserService(db))
+	app.Patch("/user/me/password", func(ctx *fiber.Ctx) error {
+		ctx.Locals("user_id", float64(account.Id))
+		return ctx.Next()
+	}, controller.ChangePasswordUser)
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"wrong old password", `{"old_password": "wrong", "new_password": "Tangerine-Kettle-42"}`, http.StatusBadRequest},
+		{"new password rejected by policy", `{"old_password": "Old-passphrase-91", "new_password": "budi1234"}`, http.StatusBadRequest},
+		{"missing old password", `{"new_password": "Tangerine-Kettle-42"}`, http.StatusBadRequest},
+		{"valid change", `{"old_password": "Old-passphrase-91", "new_password": "Tangerine-Kettle-42"}`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		request := httptest.NewRequest(http.MethodPatch, "/user/me/password", strings.NewReader(tc.body))
+		request.Header.Set("Content-Type", "application/json")
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, response.StatusCode, tc.want)
+		}
+	}
+
+	var stored User
+	if err := db.First(&stored, account.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password == nil || !helper.CheckPasswordHash("Tangerine-Kettle-42", *stored.Password) {
+		t.Error("password was not changed")
+	}
+}
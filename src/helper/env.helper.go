@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GetEnv mengambil environment variable dengan nilai default jika kosong
+func GetEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetEnvInt mengambil environment variable bertipe int, fallback jika kosong atau tidak valid
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvBool mengambil environment variable bertipe bool, fallback jika kosong atau tidak valid
+func GetEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -1,17 +1,242 @@
 package helper
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword meng-hash password tanpa error handling
-func HashPassword(password string) string {
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashed)
+// Algoritma hash password yang bisa dipilih lewat PASSWORD_HASH_ALGORITHM
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// ErrUnknownPasswordHash format hash tersimpan tidak dikenali oleh hasher mana pun
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher satu algoritma hash password. Hash tersimpan dikenali dari formatnya (Identify),
+// sehingga hash lama tetap bisa diverifikasi walaupun algoritma default sudah diganti.
+type PasswordHasher interface {
+	Name() string
+	Identify(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password string, encoded string) (bool, error)
+	// NeedsRehash true jika parameter hash lebih lemah / berbeda dari konfigurasi hasher ini
+	NeedsRehash(encoded string) bool
 }
 
-// CheckPasswordHash membandingkan password yang diinput dengan hash yang tersimpan
+// defaultPasswordHasher hasher untuk hash baru sesuai env, default argon2id
+func defaultPasswordHasher() (PasswordHasher, error) {
+	switch algorithm := GetEnv("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id); algorithm {
+	case PasswordHashArgon2id:
+		return loadArgon2idHasher(), nil
+	case PasswordHashBcrypt:
+		return BcryptHasher{Cost: GetEnvInt("BCRYPT_COST", 12)}, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q", algorithm)
+	}
+}
+
+// verifyingPasswordHashers semua hasher yang dipakai untuk mengenali hash tersimpan, termasuk format lama hasil impor
+func verifyingPasswordHashers() []PasswordHasher {
+	hashers := []PasswordHasher{loadArgon2idHasher(), BcryptHasher{Cost: GetEnvInt("BCRYPT_COST", 12)}}
+	return append(hashers, legacyPasswordHashers...)
+}
+
+// identifyPasswordHash mencari hasher yang mengenali format hash tersimpan
+func identifyPasswordHash(encoded string) (PasswordHasher, error) {
+	for _, hasher := range verifyingPasswordHashers() {
+		if hasher.Identify(encoded) {
+			return hasher, nil
+		}
+	}
+	return nil, ErrUnknownPasswordHash
+}
+
+// HashPassword meng-hash password dengan algoritma default
+func HashPassword(password string) (string, error) {
+	hasher, err := defaultPasswordHasher()
+	if err != nil {
+		return "", err
+	}
+	return hasher.Hash(password)
+}
+
+// CheckPasswordHash membandingkan password yang diinput dengan hash yang tersimpan (format apa pun yang dikenali)
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	hasher, err := identifyPasswordHash(hash)
+	if err != nil {
+		return false
+	}
+	ok, err := hasher.Verify(password, hash)
+	return err == nil && ok
+}
+
+// PasswordNeedsRehash true jika hash tersimpan memakai algoritma lain atau parameter yang sudah usang,
+// dipakai untuk meng-upgrade hash setelah login berhasil
+func PasswordNeedsRehash(hash string) bool {
+	hasher, err := defaultPasswordHasher()
+	if err != nil {
+		return false
+	}
+	if !hasher.Identify(hash) {
+		return true
+	}
+	return hasher.NeedsRehash(hash)
+}
+
+// BcryptHasher hash bcrypt; password lebih dari 72 byte ditolak, bukan dipotong diam-diam
+type BcryptHasher struct {
+	Cost int
+}
+
+// Name nama algoritma
+func (BcryptHasher) Name() string {
+	return PasswordHashBcrypt
+}
+
+// Identify mengenali prefix $2a$, $2b$, dan $2y$
+func (BcryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Hash membuat hash bcrypt dengan cost terkonfigurasi
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify mencocokkan password dengan hash bcrypt
+func (BcryptHasher) Verify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash true jika cost hash lebih rendah dari konfigurasi
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+// Argon2idHasher hash argon2id dalam format PHC: $argon2id$v=19$m=<KiB>,t=<iterasi>,p=<paralel>$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 // Dalam KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2idParams parameter yang terbaca dari hash tersimpan
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// loadArgon2idHasher parameter argon2id dari env (default mengikuti rekomendasi OWASP)
+func loadArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:      uint32(GetEnvInt("ARGON2_MEMORY", 64*1024)),
+		Iterations:  uint32(GetEnvInt("ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(GetEnvInt("ARGON2_PARALLELISM", 2)),
+		SaltLength:  uint32(GetEnvInt("ARGON2_SALT_LENGTH", 16)),
+		KeyLength:   uint32(GetEnvInt("ARGON2_KEY_LENGTH", 32)),
+	}
+}
+
+// Name nama algoritma
+func (Argon2idHasher) Name() string {
+	return PasswordHashArgon2id
+}
+
+// Identify mengenali prefix $argon2id$
+func (Argon2idHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Hash membuat hash argon2id dengan salt acak
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	if h.Memory == 0 || h.Iterations == 0 || h.Parallelism == 0 || h.SaltLength < 8 || h.KeyLength < 16 {
+		return "", fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d salt=%d key=%d",
+			h.Memory, h.Iterations, h.Parallelism, h.SaltLength, h.KeyLength)
+	}
+
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify menghitung ulang hash dengan parameter dari hash tersimpan lalu membandingkan dalam waktu konstan
+func (Argon2idHasher) Verify(password string, encoded string) (bool, error) {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// NeedsRehash true jika parameter hash tersimpan berbeda dari konfigurasi
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.Memory || params.iterations != h.Iterations || params.parallelism != h.Parallelism ||
+		uint32(len(params.salt)) < h.SaltLength || uint32(len(params.key)) != h.KeyLength
+}
+
+// parseArgon2id membaca hash argon2id berformat PHC
+func parseArgon2id(encoded string) (argon2idParams, error) {
+	var params argon2idParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, errors.New("invalid argon2id parameters")
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return params, errors.New("invalid argon2id hash")
+	}
+	return params, nil
 }
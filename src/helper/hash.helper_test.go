@@ -0,0 +1,151 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+// setFastArgon2 parameter argon2id kecil agar test cepat
+func setFastArgon2(t *testing.T) {
+	t.Helper()
+	t.Setenv("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id)
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+}
+
+func TestHashPasswordArgon2idRoundTrip(t *testing.T) {
+	setFastArgon2(t)
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("hash = %q, want argon2id PHC format with configured parameters", hash)
+	}
+	if !CheckPasswordHash("correct horse", hash) {
+		t.Error("correct password rejected")
+	}
+	if CheckPasswordHash("correct horse ", hash) {
+		t.Error("wrong password accepted")
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Error("fresh hash reported as needing rehash")
+	}
+
+	// Salt acak: hash berbeda untuk password yang sama
+	if other, _ := HashPassword("correct horse"); other == hash {
+		t.Error("two hashes of the same password are identical")
+	}
+}
+
+func TestHashPasswordBcrypt(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", PasswordHashBcrypt)
+	t.Setenv("BCRYPT_COST", "4")
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Fatalf("hash = %q, want bcrypt cost 4", hash)
+	}
+	if !CheckPasswordHash("correct horse", hash) || CheckPasswordHash("wrong", hash) {
+		t.Error("bcrypt verification mismatch")
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Error("fresh bcrypt hash reported as needing rehash")
+	}
+
+	if _, err := HashPassword(strings.Repeat("a", 73)); err == nil {
+		t.Error("password over 72 bytes was hashed instead of rejected")
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	setFastArgon2(t)
+	argonHash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := BcryptHasher{Cost: 4}.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		hash string
+		want bool
+	}{
+		{"argon2id same parameters", nil, argonHash, false},
+		{"argon2id more memory configured", map[string]string{"ARGON2_MEMORY": "2048"}, argonHash, true},
+		{"argon2id more iterations configured", map[string]string{"ARGON2_ITERATIONS": "2"}, argonHash, true},
+		{"argon2id longer key configured", map[string]string{"ARGON2_KEY_LENGTH": "64"}, argonHash, true},
+		{"bcrypt while default is argon2id", nil, bcryptHash, true},
+		{"bcrypt same cost", map[string]string{"PASSWORD_HASH_ALGORITHM": "bcrypt", "BCRYPT_COST": "4"}, bcryptHash, false},
+		{"bcrypt higher cost configured", map[string]string{"PASSWORD_HASH_ALGORITHM": "bcrypt", "BCRYPT_COST": "5"}, bcryptHash, true},
+		{"argon2id while default is bcrypt", map[string]string{"PASSWORD_HASH_ALGORITHM": "bcrypt", "BCRYPT_COST": "4"}, argonHash, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+			if got := PasswordNeedsRehash(tc.hash); got != tc.want {
+				t.Errorf("PasswordNeedsRehash = %v, want %v", got, tc.want)
+			}
+			// Hash lama tetap bisa diverifikasi setelah konfigurasi berubah
+			if !CheckPasswordHash("correct horse", tc.hash) {
+				t.Error("existing hash no longer verifies")
+			}
+		})
+	}
+}
+
+func TestHashPasswordUnknownAlgorithm(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "md5")
+	if _, err := HashPassword("correct horse"); err == nil {
+		t.Error("unknown PASSWORD_HASH_ALGORITHM accepted")
+	}
+}
+
+func TestCheckPasswordHashRejectsMalformedHashes(t *testing.T) {
+	setFastArgon2(t)
+
+	cases := []string{
+		"",
+		"plaintext",
+		"$argon2id$",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHRzYWx0$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0$",
+		"$2a$04$short",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0$aGFzaGhhc2hoYXNoaGFzaA",
+	}
+	for _, hash := range cases {
+		if CheckPasswordHash("correct horse", hash) {
+			t.Errorf("CheckPasswordHash accepted malformed hash %q", hash)
+		}
+	}
+}
+
+func TestArgon2idHasherRejectsWeakParameters(t *testing.T) {
+	cases := []Argon2idHasher{
+		{Memory: 0, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 0, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 8},
+	}
+	for _, hasher := range cases {
+		if _, err := hasher.Hash("correct horse"); err == nil {
+			t.Errorf("Hash with %+v succeeded, want error", hasher)
+		}
+	}
+}
@@ -1,11 +1,23 @@
 package helper
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -14,43 +26,393 @@ func init() {
 	_ = godotenv.Load()
 }
 
-// GenerateJWT membuat token JWT
-func GenerateJWT(userID int64, username string, fullname string, role string) (string, error) {
-	// Ambil secret key dari .env
-	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+// SigningKey menyimpan kunci untuk menandatangani dan memverifikasi JWT
+type SigningKey struct {
+	Kid         string
+	Algorithm   string
+	PrivateKey  interface{} // []byte untuk HS256, crypto.Signer untuk RS256/ES256/EdDSA
+	PublicKey   interface{} // []byte untuk HS256, crypto.PublicKey untuk algoritma asimetris
+	CreatedAt   time.Time
+	RetireAfter *time.Time
+}
 
-	// Konversi JWT_EXPIRATION ke int
-	jwtExpStr := os.Getenv("JWT_EXPIRATION")
-	jwtExp, err := strconv.Atoi(jwtExpStr)
+// JWK representasi public key dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet kumpulan JWK yang dipublikasikan di endpoint JWKS
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadSigningKey membaca JWT_ALGORITHM dan kunci tunggal yang sesuai (secret atau file PEM)
+func loadSigningKey() (*SigningKey, error) {
+	algorithm := GetEnv("JWT_ALGORITHM", "HS256")
+
+	if algorithm == "HS256" {
+		// Ambil secret key dari .env
+		jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+		if len(jwtSecret) == 0 {
+			return nil, errors.New("JWT_SECRET is not set")
+		}
+		return &SigningKey{
+			Kid:        os.Getenv("JWT_KEY_ID"),
+			Algorithm:  algorithm,
+			PrivateKey: jwtSecret,
+			PublicKey:  jwtSecret,
+		}, nil
+	}
+
+	privatePEM, err := os.ReadFile(os.Getenv("JWT_PRIVATE_KEY_PATH"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT_PRIVATE_KEY_PATH: %w", err)
+	}
+
+	key, err := parseSigningKey(algorithm, privatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	// Public key opsional, jika diisi harus cocok dengan private key
+	if publicKeyPath := os.Getenv("JWT_PUBLIC_KEY_PATH"); publicKeyPath != "" {
+		publicPEM, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT_PUBLIC_KEY_PATH: %w", err)
+		}
+		publicKey, err := parsePublicKey(algorithm, publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		if !publicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(key.PublicKey) {
+			return nil, errors.New("JWT public key does not match private key")
+		}
+	}
+
+	key.Kid = os.Getenv("JWT_KEY_ID")
+	if key.Kid == "" {
+		key.Kid = jwkThumbprint(toJWK(key))
+	}
+	return key, nil
+}
+
+// parseSigningKey mengurai private key PEM sesuai algoritma
+func parseSigningKey(algorithm string, privatePEM []byte) (*SigningKey, error) {
+	switch algorithm {
+	case "RS256":
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{Algorithm: algorithm, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	case "ES256":
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		return &SigningKey{Algorithm: algorithm, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+	case "EdDSA":
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 key")
+		}
+		return &SigningKey{Algorithm: algorithm, PrivateKey: edKey, PublicKey: edKey.Public()}, nil
+	}
+	return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", algorithm)
+}
 
+// parsePublicKey mengurai public key PEM sesuai algoritma
+func parsePublicKey(algorithm string, publicPEM []byte) (crypto.PublicKey, error) {
+	switch algorithm {
+	case "RS256":
+		return jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	case "ES256":
+		return jwt.ParseECPublicKeyFromPEM(publicPEM)
+	case "EdDSA":
+		return jwt.ParseEdPublicKeyFromPEM(publicPEM)
+	}
+	return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", algorithm)
+}
+
+// jwtExpiration membaca JWT_EXPIRATION (dalam jam), default 24 jam
+func jwtExpiration() time.Duration {
+	// Konversi JWT_EXPIRATION ke int
+	jwtExp, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION"))
 	if err != nil || jwtExp <= 0 {
 		jwtExp = 24 // Default ke 24 jam jika tidak ada atau error
 	}
-	if len(jwtSecret) == 0 {
-		jwtSecret = []byte("default_secret") // Fallback jika tidak ada ENV
+	return time.Hour * time.Duration(jwtExp)
+}
+
+// SignJWT menandatangani claims dengan kunci aktif dan menambahkan header kid
+func SignJWT(claims jwt.MapClaims) (string, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+	key, err := ring.Active()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	return token.SignedString(key.PrivateKey)
+}
+
+// AccessTokenTTL masa berlaku access token (JWT_EXPIRATION)
+func AccessTokenTTL() time.Duration {
+	return jwtExpiration()
+}
+
+// ClientTokenTTL masa berlaku access token client_credentials (dalam menit), default 60 menit
+func ClientTokenTTL() time.Duration {
+	return time.Minute * time.Duration(GetEnvInt("CLIENT_TOKEN_EXPIRATION", 60))
+}
+
+// ImpersonationTTL masa berlaku token impersonasi (dalam menit), default 15 menit
+func ImpersonationTTL() time.Duration {
+	return time.Minute * time.Duration(GetEnvInt("IMPERSONATION_EXPIRATION", 15))
+}
+
+// OAuthSessionTTL masa berlaku sesi login di halaman authorize (dalam jam), default 8 jam
+func OAuthSessionTTL() time.Duration {
+	return time.Hour * time.Duration(GetEnvInt("OAUTH_SESSION_EXPIRATION", 8))
+}
+
+// maxTokenTTL masa berlaku terpanjang dari semua token yang ditandatangani keyring,
+// kunci lama baru boleh dipensiunkan setelah token terakhirnya kedaluwarsa
+func maxTokenTTL() time.Duration {
+	return max(jwtExpiration(), ClientTokenTTL(), ImpersonationTTL(), OAuthSessionTTL())
+}
+
+// GenerateJWT membuat token JWT
+func GenerateJWT(userID int64, username string, fullname string, role string) (string, error) {
+	return SignJWT(accessTokenClaims(userID, username, fullname, role))
+}
+
+// GenerateScopedJWT membuat access token user yang diterbitkan lewat client OAuth (claim client_id & scope),
+// misal token service account milik client sendiri
+func GenerateScopedJWT(userID int64, username string, fullname string, role string, clientID string, scope string) (string, error) {
+	return SignJWT(scopedTokenClaims(userID, username, fullname, role, clientID, scope))
+}
+
+// GenerateDelegatedJWT membuat access token user yang didelegasikan ke client OAuth lewat persetujuan user
+// (grant authorization_code / device code). Claim delegated membuat token ini ditolak AuthMiddleware.
+func GenerateDelegatedJWT(userID int64, username string, fullname string, role string, clientID string, scope string) (string, error) {
+	claims := scopedTokenClaims(userID, username, fullname, role, clientID, scope)
+	claims["delegated"] = true
+	return SignJWT(claims)
+}
+
+// scopedTokenClaims claims access token user beserta client_id & scope
+func scopedTokenClaims(userID int64, username string, fullname string, role string, clientID string, scope string) jwt.MapClaims {
+	claims := accessTokenClaims(userID, username, fullname, role)
+	claims["client_id"] = clientID
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	return claims
+}
+
+// GenerateImpersonationJWT membuat access token berumur pendek atas nama user target dengan claim
+// act (RFC 8693 section 4.1) berisi admin yang sebenarnya melakukan request
+func GenerateImpersonationJWT(userID int64, username string, fullname string, role string, actorID int64, actorUsername string, ttl time.Duration) (string, error) {
+	claims := accessTokenClaims(userID, username, fullname, role)
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	claims["act"] = map[string]interface{}{
+		"sub":      strconv.FormatInt(actorID, 10),
+		"user_id":  actorID,
+		"username": actorUsername,
+	}
+	return SignJWT(claims)
+}
+
+// GenerateClientJWT membuat access token untuk client OAuth tanpa user (grant client_credentials).
+// Claim subject_type "client" membedakannya dari token user.
+func GenerateClientJWT(clientID string, scope string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti":          uuid.NewString(),
+		"token_use":    "access",
+		"subject_type": "client",
+		"sub":          clientID,
+		"client_id":    clientID,
+		"iat":          now.Unix(),
+		"exp":          now.Add(ttl).Unix(),
+	}
+	if scope != "" {
+		claims["scope"] = scope
 	}
+	return SignJWT(claims)
+}
 
+// accessTokenClaims menyusun claims standar access token user
+func accessTokenClaims(userID int64, username string, fullname string, role string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"jti":       uuid.NewString(),
+		"token_use": "access",
+		"user_id":   userID,
+		"username":  username,
+		"fullname":  fullname,
+		"role":      role,
+		"iat":       now.Unix(),
+		"iat_us":    now.UnixMicro(),
+		"exp":       now.Add(jwtExpiration()).Unix(),
+	}
+}
+
+// GeneratePurposeToken membuat JWT berumur pendek untuk keperluan khusus (misal tantangan MFA).
+// Token ini ditolak oleh AuthMiddleware karena token_use bukan "access".
+func GeneratePurposeToken(purpose string, userID int64, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"fullname": username,
-		"role":     role,
-		"exp":      time.Now().Add(time.Hour * time.Duration(jwtExp)).Unix(),
+		"jti":       uuid.NewString(),
+		"token_use": purpose,
+		"user_id":   userID,
+		"iat":       now.Unix(),
+		"iat_us":    now.UnixMicro(),
+		"exp":       now.Add(ttl).Unix(),
+	}
+
+	return SignJWT(claims)
+}
+
+// TokenIssuedAt waktu terbit token untuk cek pencabutan. Claim iat_us (mikrodetik) dipakai jika ada karena iat
+// dibulatkan ke detik; token lama tanpa iat_us memakai iat sehingga ikut dicabut jika terbit di detik pencabutan.
+func TokenIssuedAt(claims jwt.MapClaims) (time.Time, bool) {
+	if micros, ok := claims["iat_us"].(float64); ok {
+		return time.UnixMicro(int64(micros)), true
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return time.Time{}, false
+	}
+	return issuedAt.Time, true
+}
+
+// ValidatePurposeToken memvalidasi JWT khusus dan memastikan token_use sesuai tujuan
+func ValidatePurposeToken(tokenString string, purpose string) (jwt.MapClaims, error) {
+	token, err := ValidateJWT(tokenString)
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired token")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["token_use"] != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	return claims, nil
 }
 
-// ValidateJWT memvalidasi token JWT
+// ValidateJWT memvalidasi token JWT, kunci verifikasi dipilih berdasarkan header kid
 func ValidateJWT(tokenString string) (*jwt.Token, error) {
-	// Ambil secret key dari .env
-	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
-	if len(jwtSecret) == 0 {
-		jwtSecret = []byte("default_secret") // Fallback jika tidak ada ENV
+	ring, err := getKeyring()
+	if err != nil {
+		return nil, err
 	}
 
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, err := ring.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// Algoritma token harus sama dengan algoritma kunci untuk mencegah algorithm confusion
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"HS256", "RS256", "ES256", "EdDSA"}))
+}
+
+// GetJWKS mengembalikan public key yang masih berlaku dalam format JWKS (kunci HS256 tidak dipublikasikan)
+func GetJWKS() JWKSet {
+	jwks := JWKSet{Keys: []JWK{}}
+
+	ring, err := getKeyring()
+	if err != nil {
+		return jwks
+	}
+
+	for _, key := range ring.Verifiable() {
+		if key.Algorithm != "HS256" {
+			jwks.Keys = append(jwks.Keys, toJWK(key))
+		}
+	}
+	return jwks
+}
+
+// toJWK mengubah public key menjadi JWK
+func toJWK(key *SigningKey) JWK {
+	jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Algorithm}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, _ := publicKey.ECDH()
+		raw := ecdhKey.Bytes() // 0x04 || X || Y
+		size := (len(raw) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(raw[1 : 1+size])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(raw[1+size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+	return jwk
+}
+
+// jwkThumbprint menghitung JWK thumbprint (RFC 7638) sebagai kid default
+func jwkThumbprint(jwk JWK) string {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	encoded, _ := json.Marshal(members)
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
 }
@@ -0,0 +1,20 @@
+package helper
+
+import "testing"
+
+func TestMissingSecretsAreRejected(t *testing.T) {
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("JWT_SECRET", "")
+	if _, err := loadSigningKey(); err == nil {
+		t.Error("HS256 signing key loaded without JWT_SECRET")
+	}
+
+	t.Setenv("ENCRYPTION_KEY", "")
+	if err := SetupEncryptionKey(); err == nil {
+		t.Error("encryption key loaded without ENCRYPTION_KEY")
+	}
+	t.Setenv("ENCRYPTION_KEY", "dG9vLXNob3J0")
+	if err := SetupEncryptionKey(); err == nil {
+		t.Error("encryption key loaded from a value that is not 32 bytes")
+	}
+}
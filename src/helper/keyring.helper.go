@@ -0,0 +1,403 @@
+package helper
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KeyMetadata informasi kunci penandatangan yang disimpan berdampingan dengan file kunci
+type KeyMetadata struct {
+	Kid         string     `json:"kid"`
+	Algorithm   string     `json:"algorithm"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RetireAfter *time.Time `json:"retire_after"`
+	Active      bool       `json:"active"`
+}
+
+// Keyring menyimpan beberapa kunci sekaligus: satu aktif untuk menandatangani,
+// sisanya hanya untuk verifikasi sampai RetireAfter terlewati
+type Keyring struct {
+	Dir string // kosong berarti kunci tunggal dari env (tanpa rotasi)
+
+	mu       sync.RWMutex
+	keys     map[string]*SigningKey
+	active   *SigningKey
+	loadedAt time.Time
+}
+
+var (
+	keyring     *Keyring
+	keyringErr  error
+	keyringOnce sync.Once
+)
+
+// keyringReloadInterval jeda minimal sebelum direktori kunci dibaca ulang (agar rotasi di instance lain terlihat)
+const keyringReloadInterval = time.Minute
+
+// SetupSigningKey memuat kunci JWT dari environment, dipanggil saat aplikasi start agar gagal lebih awal
+func SetupSigningKey() error {
+	_, err := getKeyring()
+	return err
+}
+
+// getKeyring memuat keyring sekali saja
+func getKeyring() (*Keyring, error) {
+	keyringOnce.Do(func() {
+		keyring, keyringErr = loadKeyring()
+	})
+	return keyring, keyringErr
+}
+
+// loadKeyring memakai JWT_KEYS_DIR jika diisi, selain itu kunci tunggal dari JWT_SECRET / JWT_PRIVATE_KEY_PATH
+func loadKeyring() (*Keyring, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		key, err := loadSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		return &Keyring{
+			keys:   map[string]*SigningKey{key.Kid: key},
+			active: key,
+		}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	ring := &Keyring{Dir: dir}
+	if err := ring.reload(); err != nil {
+		return nil, err
+	}
+
+	// Direktori kosong: buat kunci pertama
+	if ring.active == nil {
+		if _, err := ring.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return ring, nil
+}
+
+// reload membaca ulang semua kunci dari direktori
+func (k *Keyring) reload() error {
+	files, err := filepath.Glob(filepath.Join(k.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey)
+	var active *SigningKey
+	for _, file := range files {
+		key, err := readKeyFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", filepath.Base(file), err)
+		}
+		keys[key.Kid] = key
+
+		if key.RetireAfter == nil && (active == nil || key.CreatedAt.After(active.CreatedAt)) {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// maybeReload membaca ulang direktori jika cache sudah lama atau kid tidak dikenal
+func (k *Keyring) maybeReload(force bool) {
+	if k.Dir == "" {
+		return
+	}
+
+	k.mu.RLock()
+	age := time.Since(k.loadedAt)
+	k.mu.RUnlock()
+
+	// Reload paksa (kid tidak dikenal) tetap dibatasi agar tidak bisa dipakai membanjiri disk
+	if age > keyringReloadInterval || (force && age > 5*time.Second) {
+		_ = k.reload()
+	}
+}
+
+// Active mengembalikan kunci yang dipakai untuk menandatangani token baru
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.maybeReload(false)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	return k.active, nil
+}
+
+// Lookup mencari kunci verifikasi berdasarkan kid; kid kosong memakai kunci aktif
+func (k *Keyring) Lookup(kid string) (*SigningKey, error) {
+	if kid == "" {
+		return k.Active()
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		k.maybeReload(true)
+		k.mu.RLock()
+		key, ok = k.keys[kid]
+		k.mu.RUnlock()
+	}
+
+	if !ok || key.Retired(time.Now()) {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
+
+// Verifiable mengembalikan semua kunci yang masih diterima untuk verifikasi
+func (k *Keyring) Verifiable() []*SigningKey {
+	k.maybeReload(false)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.Retired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys
+}
+
+// Metadata mengembalikan informasi semua kunci (tanpa materi kunci)
+func (k *Keyring) Metadata() []KeyMetadata {
+	keys := k.Verifiable()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	metadata := make([]KeyMetadata, 0, len(keys))
+	for _, key := range keys {
+		metadata = append(metadata, key.metadata(key == k.active))
+	}
+	return metadata
+}
+
+// Rotate membuat kunci aktif baru dan menandai kunci aktif lama untuk pensiun
+// setelah semua token yang ditandatanganinya kedaluwarsa
+func (k *Keyring) Rotate() (KeyMetadata, error) {
+	if k.Dir == "" {
+		return KeyMetadata{}, errors.New("key rotation requires JWT_KEYS_DIR")
+	}
+
+	algorithm := GetEnv("JWT_ALGORITHM", "HS256")
+	key, err := generateSigningKey(algorithm)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	if err := writeKeyFile(k.Dir, key); err != nil {
+		return KeyMetadata{}, err
+	}
+
+	k.mu.RLock()
+	previous := k.active
+	k.mu.RUnlock()
+
+	if previous != nil {
+		// Beri jeda tambahan untuk clock skew antar server
+		retireAfter := time.Now().UTC().Add(maxTokenTTL() + 5*time.Minute)
+		retiring := *previous
+		retiring.RetireAfter = &retireAfter
+		if err := writeKeyMetadata(k.Dir, &retiring); err != nil {
+			return KeyMetadata{}, err
+		}
+	}
+
+	if err := k.reload(); err != nil {
+		return KeyMetadata{}, err
+	}
+	return key.metadata(true), nil
+}
+
+// Retired mengecek apakah kunci sudah melewati masa pensiun
+func (key *SigningKey) Retired(now time.Time) bool {
+	return key.RetireAfter != nil && now.After(*key.RetireAfter)
+}
+
+func (key *SigningKey) metadata(active bool) KeyMetadata {
+	return KeyMetadata{
+		Kid:         key.Kid,
+		Algorithm:   key.Algorithm,
+		CreatedAt:   key.CreatedAt,
+		RetireAfter: key.RetireAfter,
+		Active:      active,
+	}
+}
+
+// RotateSigningKey merotasi kunci penandatangan melalui keyring default
+func RotateSigningKey() (KeyMetadata, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	return ring.Rotate()
+}
+
+// ListSigningKeys mengembalikan metadata kunci melalui keyring default
+func ListSigningKeys() ([]KeyMetadata, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return ring.Metadata(), nil
+}
+
+// ActiveSigningAlgorithm mengembalikan algoritma kunci aktif (misal untuk discovery OIDC)
+func ActiveSigningAlgorithm() (string, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+	key, err := ring.Active()
+	if err != nil {
+		return "", err
+	}
+	return key.Algorithm, nil
+}
+
+// generateSigningKey membuat kunci baru sesuai algoritma
+func generateSigningKey(algorithm string) (*SigningKey, error) {
+	key := &SigningKey{Algorithm: algorithm, CreatedAt: time.Now().UTC()}
+
+	switch algorithm {
+	case "HS256":
+		secret := make([]byte, 64)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = secret, secret
+		key.Kid = uuid.NewString() // kid tidak boleh diturunkan dari secret
+		return key, nil
+	case "RS256":
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	case "ES256":
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, &privateKey.PublicKey
+	case "EdDSA":
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = privateKey, publicKey
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", algorithm)
+	}
+
+	key.Kid = jwkThumbprint(toJWK(key))
+	return key, nil
+}
+
+// writeKeyFile menyimpan materi kunci (<kid>.pem / <kid>.key) dan metadatanya (<kid>.json)
+func writeKeyFile(dir string, key *SigningKey) error {
+	if secret, ok := key.PrivateKey.([]byte); ok {
+		encoded := base64.StdEncoding.EncodeToString(secret)
+		if err := os.WriteFile(filepath.Join(dir, key.Kid+".key"), []byte(encoded), 0o600); err != nil {
+			return err
+		}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+		if err != nil {
+			return err
+		}
+		encoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, key.Kid+".pem"), encoded, 0o600); err != nil {
+			return err
+		}
+	}
+	return writeKeyMetadata(dir, key)
+}
+
+// writeKeyMetadata menulis metadata kunci secara atomik
+func writeKeyMetadata(dir string, key *SigningKey) error {
+	encoded, err := json.MarshalIndent(key.metadata(false), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := filepath.Join(dir, key.Kid+".json.tmp")
+	if err := os.WriteFile(tmpFile, encoded, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filepath.Join(dir, key.Kid+".json"))
+}
+
+// readKeyFile membaca metadata dan materi kunci dari direktori
+func readKeyFile(metadataFile string) (*SigningKey, error) {
+	encoded, err := os.ReadFile(metadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata KeyMetadata
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return nil, err
+	}
+
+	basePath := strings.TrimSuffix(metadataFile, ".json")
+	var key *SigningKey
+	if metadata.Algorithm == "HS256" {
+		encodedSecret, err := os.ReadFile(basePath + ".key")
+		if err != nil {
+			return nil, err
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSecret)))
+		if err != nil {
+			return nil, err
+		}
+		key = &SigningKey{Algorithm: metadata.Algorithm, PrivateKey: secret, PublicKey: secret}
+	} else {
+		privatePEM, err := os.ReadFile(basePath + ".pem")
+		if err != nil {
+			return nil, err
+		}
+		if key, err = parseSigningKey(metadata.Algorithm, privatePEM); err != nil {
+			return nil, err
+		}
+	}
+
+	key.Kid = metadata.Kid
+	key.CreatedAt = metadata.CreatedAt
+	key.RetireAfter = metadata.RetireAfter
+	return key, nil
+}
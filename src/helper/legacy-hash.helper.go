@@ -0,0 +1,341 @@
+package helper
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrLegacyHashVerifyOnly hash format lama hanya untuk verifikasi user hasil impor, tidak untuk membuat hash baru
+var ErrLegacyHashVerifyOnly = errors.New("legacy password hash formats are verify-only")
+
+// legacyPasswordHashers format hash dari sistem lain yang diterima di kolom users.password.
+// Hash ini selalu dianggap usang sehingga di-upgrade ke algoritma default pada login pertama yang berhasil.
+var legacyPasswordHashers = []PasswordHasher{
+	DjangoPBKDF2Hasher{},
+	PHPassHasher{},
+	SaltedDigestHasher{},
+	SHA512CryptHasher{},
+}
+
+// cryptAlphabet alfabet base64 ala crypt(3) yang dipakai phpass dan SHA-crypt
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// DjangoPBKDF2Hasher format Django: pbkdf2_sha256$<iterasi>$<salt>$<base64 hash>
+type DjangoPBKDF2Hasher struct{}
+
+// Name nama format
+func (DjangoPBKDF2Hasher) Name() string {
+	return "pbkdf2_sha256"
+}
+
+// Identify mengenali prefix pbkdf2_sha256$
+func (DjangoPBKDF2Hasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "pbkdf2_sha256$")
+}
+
+// Hash tidak didukung untuk format lama
+func (DjangoPBKDF2Hasher) Hash(string) (string, error) {
+	return "", ErrLegacyHashVerifyOnly
+}
+
+// Verify menghitung PBKDF2-HMAC-SHA256 dengan iterasi dan salt dari hash tersimpan
+func (DjangoPBKDF2Hasher) Verify(password string, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return false, ErrUnknownPasswordHash
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, fmt.Errorf("invalid pbkdf2_sha256 iterations %q", parts[1])
+	}
+	expected, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false, errors.New("invalid pbkdf2_sha256 hash")
+	}
+
+	key := pbkdf2.Key([]byte(password), []byte(parts[2]), iterations, len(expected), sha256.New)
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// NeedsRehash format lama selalu di-upgrade
+func (DjangoPBKDF2Hasher) NeedsRehash(string) bool {
+	return true
+}
+
+// PHPassHasher portable hash phpass (WordPress $P$, phpBB $H$)
+type PHPassHasher struct{}
+
+// Name nama format
+func (PHPassHasher) Name() string {
+	return "phpass"
+}
+
+// Identify mengenali prefix $P$ dan $H$
+func (PHPassHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$P$") || strings.HasPrefix(encoded, "$H$")
+}
+
+// Hash tidak didukung untuk format lama
+func (PHPassHasher) Hash(string) (string, error) {
+	return "", ErrLegacyHashVerifyOnly
+}
+
+// Verify mengulang MD5 sebanyak 2^n kali sesuai setting di hash tersimpan
+func (PHPassHasher) Verify(password string, encoded string) (bool, error) {
+	if len(encoded) != 34 {
+		return false, ErrUnknownPasswordHash
+	}
+	countLog2 := strings.IndexByte(cryptAlphabet, encoded[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return false, errors.New("invalid phpass iteration count")
+	}
+	salt := encoded[4:12]
+
+	sum := md5.Sum([]byte(salt + password))
+	for count := 1 << countLog2; count > 0; count-- {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+
+	computed := encoded[:12] + phpassEncode64(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
+
+// NeedsRehash format lama selalu di-upgrade
+func (PHPassHasher) NeedsRehash(string) bool {
+	return true
+}
+
+// phpassEncode64 encoding base64 little-endian milik phpass
+func phpassEncode64(input []byte) string {
+	var output strings.Builder
+	for i := 0; i < len(input); i += 3 {
+		value := int(input[i])
+		output.WriteByte(cryptAlphabet[value&0x3f])
+		if i+1 < len(input) {
+			value |= int(input[i+1]) << 8
+		}
+		output.WriteByte(cryptAlphabet[(value>>6)&0x3f])
+		if i+1 >= len(input) {
+			break
+		}
+		if i+2 < len(input) {
+			value |= int(input[i+2]) << 16
+		}
+		output.WriteByte(cryptAlphabet[(value>>12)&0x3f])
+		if i+2 >= len(input) {
+			break
+		}
+		output.WriteByte(cryptAlphabet[(value>>18)&0x3f])
+	}
+	return output.String()
+}
+
+// SaltedDigestHasher hash SHA1 / MD5 bersalt dengan format tag sha1$<salt>$<hex> atau md5$<salt>$<hex>,
+// digest dihitung dari salt diikuti password (sama dengan format lama Django). Hash dari aplikasi lain
+// perlu dikonversi ke format ini saat impor.
+type SaltedDigestHasher struct{}
+
+// Name nama format
+func (SaltedDigestHasher) Name() string {
+	return "salted_digest"
+}
+
+// Identify mengenali prefix sha1$ dan md5$
+func (SaltedDigestHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "sha1$") || strings.HasPrefix(encoded, "md5$")
+}
+
+// Hash tidak didukung untuk format lama
+func (SaltedDigestHasher) Hash(string) (string, error) {
+	return "", ErrLegacyHashVerifyOnly
+}
+
+// Verify menghitung digest(salt + password) lalu membandingkan dalam waktu konstan
+func (SaltedDigestHasher) Verify(password string, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 {
+		return false, ErrUnknownPasswordHash
+	}
+
+	var digest hash.Hash
+	switch parts[0] {
+	case "sha1":
+		digest = sha1.New()
+	case "md5":
+		digest = md5.New()
+	default:
+		return false, ErrUnknownPasswordHash
+	}
+	expected, err := hex.DecodeString(strings.ToLower(parts[2]))
+	if err != nil || len(expected) != digest.Size() {
+		return false, fmt.Errorf("invalid %s hash", parts[0])
+	}
+
+	digest.Write([]byte(parts[1] + password))
+	return subtle.ConstantTimeCompare(digest.Sum(nil), expected) == 1, nil
+}
+
+// NeedsRehash format lama selalu di-upgrade
+func (SaltedDigestHasher) NeedsRehash(string) bool {
+	return true
+}
+
+// SHA512CryptHasher crypt(3) SHA-512: $6$[rounds=<n>$]<salt>$<hash>
+type SHA512CryptHasher struct{}
+
+// Batas rounds SHA-crypt sesuai spesifikasi
+const (
+	sha512CryptDefaultRounds = 5000
+	sha512CryptMinRounds     = 1000
+	sha512CryptMaxRounds     = 999999999
+)
+
+// Name nama format
+func (SHA512CryptHasher) Name() string {
+	return "sha512_crypt"
+}
+
+// Identify mengenali prefix $6$
+func (SHA512CryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$6$")
+}
+
+// Hash tidak didukung untuk format lama
+func (SHA512CryptHasher) Hash(string) (string, error) {
+	return "", ErrLegacyHashVerifyOnly
+}
+
+// Verify menghitung ulang SHA-crypt dengan salt dan rounds dari hash tersimpan
+func (SHA512CryptHasher) Verify(password string, encoded string) (bool, error) {
+	setting := strings.TrimPrefix(encoded, "$6$")
+	rounds, customRounds := sha512CryptDefaultRounds, false
+	if strings.HasPrefix(setting, "rounds=") {
+		value, rest, ok := strings.Cut(strings.TrimPrefix(setting, "rounds="), "$")
+		parsed, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			return false, errors.New("invalid sha512_crypt rounds")
+		}
+		rounds = min(max(parsed, sha512CryptMinRounds), sha512CryptMaxRounds)
+		customRounds = true
+		setting = rest
+	}
+
+	salt, _, ok := strings.Cut(setting, "$")
+	if !ok {
+		return false, ErrUnknownPasswordHash
+	}
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+
+	computed := sha512Crypt([]byte(password), []byte(salt), rounds, customRounds)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
+
+// NeedsRehash format lama selalu di-upgrade
+func (SHA512CryptHasher) NeedsRehash(string) bool {
+	return true
+}
+
+// sha512Crypt implementasi SHA-crypt (Ulrich Drepper) untuk SHA-512
+func sha512Crypt(password []byte, salt []byte, rounds int, customRounds bool) string {
+	// Digest B = SHA512(password + salt + password)
+	alternate := sha512.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alternateSum := alternate.Sum(nil)
+
+	// Digest A
+	digest := sha512.New()
+	digest.Write(password)
+	digest.Write(salt)
+	digest.Write(repeatBytes(alternateSum, len(password)))
+	for length := len(password); length > 0; length >>= 1 {
+		if length&1 != 0 {
+			digest.Write(alternateSum)
+		} else {
+			digest.Write(password)
+		}
+	}
+	sum := digest.Sum(nil)
+
+	// Urutan P (dari password) dan S (dari salt)
+	passwordDigest := sha512.New()
+	for range password {
+		passwordDigest.Write(password)
+	}
+	passwordSeq := repeatBytes(passwordDigest.Sum(nil), len(password))
+
+	saltDigest := sha512.New()
+	for i := 0; i < 16+int(sum[0]); i++ {
+		saltDigest.Write(salt)
+	}
+	saltSeq := repeatBytes(saltDigest.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		round := sha512.New()
+		if i&1 != 0 {
+			round.Write(passwordSeq)
+		} else {
+			round.Write(sum)
+		}
+		if i%3 != 0 {
+			round.Write(saltSeq)
+		}
+		if i%7 != 0 {
+			round.Write(passwordSeq)
+		}
+		if i&1 != 0 {
+			round.Write(sum)
+		} else {
+			round.Write(passwordSeq)
+		}
+		sum = round.Sum(nil)
+	}
+
+	var output strings.Builder
+	output.WriteString("$6$")
+	if customRounds {
+		output.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	output.Write(salt)
+	output.WriteByte('$')
+
+	// Permutasi byte hasil sesuai spesifikasi SHA-crypt
+	for i := 0; i < 21; i++ {
+		a, b, c := sum[(i*22)%63], sum[(i*22+21)%63], sum[(i*22+42)%63]
+		writeCrypt64(&output, uint(a)<<16|uint(b)<<8|uint(c), 4)
+	}
+	writeCrypt64(&output, uint(sum[63]), 2)
+	return output.String()
+}
+
+// repeatBytes mengulang source sampai panjangnya length
+func repeatBytes(source []byte, length int) []byte {
+	result := make([]byte, length)
+	for i := range result {
+		result[i] = source[i%len(source)]
+	}
+	return result
+}
+
+// writeCrypt64 menulis n karakter base64 crypt(3) dari 6 bit terendah value
+func writeCrypt64(output *strings.Builder, value uint, n int) {
+	for ; n > 0; n-- {
+		output.WriteByte(cryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+}
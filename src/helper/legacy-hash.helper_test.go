@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLegacyPasswordHashVectors(t *testing.T) {
+	cases := []struct {
+		name     string
+		hash     string
+		password string
+	}{
+		{"django pbkdf2_sha256", "pbkdf2_sha256$1000$abcSALT$5pUOjXbFBS/sLz0ZF4mybHeH7PSsWzmKlP9lKwMRFcE=", "hunter2"},
+		{"phpass portable", "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", "test12345"},
+		{"salted sha1", "sha1$xyz$3fb9edc39d74211f0081e8b59b783bb6eeaa992c", "hunter2"},
+		{"salted md5", "md5$xyz$2db80806881af97754854036c3078bad", "hunter2"},
+		// Vektor pertama dari spesifikasi SHA-crypt (Drepper), sisanya dibuat dengan crypt(3) glibc
+		{"sha512-crypt default rounds", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", "Hello world!"},
+		{"sha512-crypt custom rounds", "$6$rounds=2000$somesalt$yhZ4zNmGTPiV7IHGMcykHVJOUMC.rV1CEIrU5fe1XMvn3/MrtfwlRekSj.wDty6Loaor1urBYioMzArh4OIvx/", "hunter2"},
+		{"sha512-crypt 16 character salt", "$6$abcdefghijklmnop$EC.xeLW9zNWcX0r23FSpQaV7PG.Ibd4QnLe3w6UC47i3/vkPQouEDwvUpGtqFiad5mzQG96cD/LywQiXv9WfH/", "hunter2"},
+		{"sha512-crypt empty password", "$6$x$QSmr1Bx2g4O6BzKvdkgOcyU6H91X6I/XBv5pSalMhSPkwdH6Beo3F455xZJg0v//bxVK5F4OE5k1.0xuR26MK0", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !CheckPasswordHash(tc.password, tc.hash) {
+				t.Error("correct password rejected")
+			}
+			if CheckPasswordHash(tc.password+"x", tc.hash) {
+				t.Error("wrong password accepted")
+			}
+			// Format lama selalu di-upgrade ke algoritma default setelah login
+			if !PasswordNeedsRehash(tc.hash) {
+				t.Error("legacy hash not marked for rehash")
+			}
+		})
+	}
+}
+
+func TestLegacyPasswordHashersAreVerifyOnly(t *testing.T) {
+	for _, hasher := range legacyPasswordHashers {
+		if _, err := hasher.Hash("hunter2"); !errors.Is(err, ErrLegacyHashVerifyOnly) {
+			t.Errorf("%s: Hash error = %v, want ErrLegacyHashVerifyOnly", hasher.Name(), err)
+		}
+	}
+}
+
+func TestLegacyPasswordHashRejectsMalformed(t *testing.T) {
+	cases := []string{
+		"pbkdf2_sha256$abc$salt$5pUOjXbFBS/sLz0ZF4mybHeH7PSsWzmKlP9lKwMRFcE=",
+		"pbkdf2_sha256$1000$abcSALT",
+		"pbkdf2_sha256$1000$abcSALT$not-base64!",
+		"$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r",
+		"$P$!IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0",
+		"sha1$xyz$3fb9edc39d74211f0081e8b59b783bb6eeaa99",
+		"sha1$xyz$zzzz",
+		"md5$xyz",
+		"$6$rounds=abc$somesalt$yhZ4zNmGTPiV7IHGMcykHVJOUMC",
+		"$6$nosalthash",
+	}
+	for _, hash := range cases {
+		if CheckPasswordHash("hunter2", hash) {
+			t.Errorf("CheckPasswordHash accepted malformed hash %q", hash)
+		}
+	}
+}
+
+func TestPHPassEncode64(t *testing.T) {
+	cases := []struct {
+		input []byte
+		want  string
+	}{
+		{[]byte{}, ""},
+		{[]byte{0x00}, ".."},
+		{[]byte{0xff}, "z1"},
+		{[]byte{0x00, 0x00, 0x00}, "...."},
+		{[]byte{0xff, 0xff, 0xff}, "zzzz"},
+	}
+	for _, tc := range cases {
+		if got := phpassEncode64(tc.input); got != tc.want {
+			t.Errorf("phpassEncode64(%x) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package helper
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mail adalah pesan email yang akan dikirim
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer interface pengirim email, implementasi dipilih lewat MAIL_DRIVER
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// NewMailer membuat Mailer sesuai MAIL_DRIVER (smtp | log), default log untuk development
+func NewMailer() Mailer {
+	switch GetEnv("MAIL_DRIVER", "log") {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     GetEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     GetEnv("MAIL_FROM", "no-reply@localhost"),
+		}
+	default:
+		return &LogMailer{Path: os.Getenv("MAIL_LOG_PATH")}
+	}
+}
+
+// SMTPMailer mengirim email melalui server SMTP
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send mengirim email plain text melalui SMTP
+func (m *SMTPMailer) Send(mail Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// Cegah header injection dari nilai yang berasal dari input user
+	stripNewlines := strings.NewReplacer("\r", "", "\n", "")
+	to := stripNewlines.Replace(mail.To)
+
+	message := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + stripNewlines.Replace(mail.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		mail.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(message))
+}
+
+// LogMailer menulis email ke log atau file, untuk development lokal
+type LogMailer struct {
+	Path string // kosong berarti ditulis ke log
+
+	mu sync.Mutex
+}
+
+// Send menulis isi email ke file (append) atau log
+func (m *LogMailer) Send(mail Mail) error {
+	entry := fmt.Sprintf("[%s] To: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), mail.To, mail.Subject, mail.Body)
+	if m.Path == "" {
+		log.Print(entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(entry)
+	return err
+}
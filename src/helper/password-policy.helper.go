@@ -0,0 +1,302 @@
+package helper
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed data/common-passwords.txt
+var commonPasswordsFile string
+
+// commonPasswordList daftar password umum (huruf kecil) terurut dari yang paling umum
+type commonPasswordList struct {
+	words []string
+	ranks map[string]int // Peringkat, 1 = paling umum
+}
+
+// commonPasswords daftar bawaan, dibaca sekali dari file embed
+var commonPasswords = sync.OnceValue(func() commonPasswordList {
+	list := commonPasswordList{ranks: map[string]int{}}
+	for _, line := range strings.Split(commonPasswordsFile, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if _, exists := list.ranks[word]; word != "" && !exists {
+			list.words = append(list.words, word)
+			list.ranks[word] = len(list.words)
+		}
+	}
+	return list
+})
+
+// PasswordPolicy aturan password untuk register, pembuatan user, reset, dan ganti password
+type PasswordPolicy struct {
+	MinLength       int
+	MaxLength       int
+	RequireUpper    bool
+	RequireLower    bool
+	RequireDigit    bool
+	RequireSymbol   bool
+	DisallowPersona bool // Tolak password yang mengandung username / nama / email
+	DisallowCommon  bool // Tolak password yang ada di daftar password umum
+	MinScore        int  // Skor kekuatan minimal 0-4 (lihat PasswordStrength), 0 berarti tidak dicek
+}
+
+// LoadPasswordPolicy aturan password dari env
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:       GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:       GetEnvInt("PASSWORD_MAX_LENGTH", 128),
+		RequireUpper:    GetEnvBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		RequireLower:    GetEnvBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		RequireDigit:    GetEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol:   GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		DisallowPersona: GetEnvBool("PASSWORD_DISALLOW_PERSONAL_INFO", true),
+		DisallowCommon:  GetEnvBool("PASSWORD_DISALLOW_COMMON", true),
+		MinScore:        minScoreFromEnv(),
+	}
+}
+
+// minScoreFromEnv membaca PASSWORD_MIN_SCORE, nilai 0 diterima untuk mematikan cek skor
+func minScoreFromEnv() int {
+	value, err := strconv.Atoi(strings.TrimSpace(os.Getenv("PASSWORD_MIN_SCORE")))
+	if err != nil || value < 0 || value > 4 {
+		return 2
+	}
+	return value
+}
+
+// ValidatePassword memeriksa password terhadap policy dari env, lihat PasswordPolicy.Validate
+func ValidatePassword(password string, personalInfo ...string) []string {
+	return LoadPasswordPolicy().Validate(password, personalInfo...)
+}
+
+// Validate mengembalikan satu pesan untuk setiap aturan yang dilanggar, kosong jika password diterima.
+// personalInfo berisi data user (username, nama lengkap, email) yang tidak boleh dipakai di password.
+func (p PasswordPolicy) Validate(password string, personalInfo ...string) []string {
+	var errors []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		errors = append(errors, fmt.Sprintf("Password minimal harus %d karakter", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// Langsung ditolak: aturan lain (terutama estimasi kekuatan) mahal untuk input yang sangat panjang
+		return []string{fmt.Sprintf("Password maksimal %d karakter", p.MaxLength)}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		errors = append(errors, "Password harus mengandung huruf besar")
+	}
+	if p.RequireLower && !hasLower {
+		errors = append(errors, "Password harus mengandung huruf kecil")
+	}
+	if p.RequireDigit && !hasDigit {
+		errors = append(errors, "Password harus mengandung angka")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		errors = append(errors, "Password harus mengandung simbol")
+	}
+
+	userInputs := personalTokens(personalInfo)
+	if p.DisallowPersona && containsAny(strings.ToLower(password), userInputs) {
+		errors = append(errors, "Password tidak boleh mengandung username, nama, atau email")
+	}
+
+	if p.DisallowCommon && isCommonPassword(password) {
+		errors = append(errors, "Password terlalu umum dan mudah ditebak")
+	} else if p.MinScore > 0 {
+		if score := PasswordStrength(password, userInputs...); score < p.MinScore {
+			errors = append(errors, fmt.Sprintf("Password terlalu lemah (skor %d dari 4, minimal %d)", score, p.MinScore))
+		}
+	}
+
+	return errors
+}
+
+// personalTokens memecah data user menjadi potongan (huruf kecil, minimal 3 karakter) yang dicari di password
+func personalTokens(values []string) []string {
+	var tokens []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+		for _, token := range append(strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}), strings.ReplaceAll(value, " ", "")) {
+			if utf8.RuneCountInString(token) >= 3 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// containsAny true jika text mengandung salah satu token
+func containsAny(text string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(text, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommonPassword true jika password (atau versinya tanpa substitusi leet) ada di daftar password umum
+func isCommonPassword(password string) bool {
+	lower := strings.ToLower(password)
+	_, common := commonPasswords().ranks[lower]
+	_, commonLeet := commonPasswords().ranks[unleet(lower)]
+	return common || commonLeet
+}
+
+// unleet mengembalikan substitusi leet yang umum (p@ssw0rd -> password)
+func unleet(text string) string {
+	return strings.NewReplacer("@", "a", "4", "a", "0", "o", "1", "i", "!", "i", "3", "e", "$", "s", "5", "s", "7", "t").Replace(text)
+}
+
+// keyboardRows baris keyboard untuk mendeteksi pola seperti "qwerty" dan "asdf"
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// PasswordStrength skor kekuatan password 0-4 ala zxcvbn dari perkiraan jumlah tebakan:
+// 0 < 10^3, 1 < 10^6, 2 < 10^8, 3 < 10^10, 4 selebihnya. Pengulangan, urutan (abc, 123, qwerty),
+// kata dari daftar password umum, dan data pribadi (userInputs) dihitung murah untuk ditebak.
+func PasswordStrength(password string, userInputs ...string) int {
+	guesses := estimateGuessesLog10(password, userInputs)
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// estimateGuessesLog10 perkiraan log10 jumlah tebakan, dihitung per karakter lalu dikurangi untuk pola yang dikenali
+func estimateGuessesLog10(password string, userInputs []string) float64 {
+	if password == "" || isCommonPassword(password) {
+		return 0
+	}
+
+	lower := strings.ToLower(password)
+	chars := []rune(lower)
+	perChar := math.Log10(float64(charsetSize(password)))
+	cost := make([]float64, len(chars))
+	for i := range chars {
+		cost[i] = perChar
+		if i > 0 && (chars[i] == chars[i-1] || isSequential(chars[i-1], chars[i])) {
+			cost[i] = math.Log10(2)
+		}
+	}
+
+	// Kata yang dikenali dihitung sebagai satu tebakan dari daftar: biaya = log10(peringkat).
+	// Dicocokkan pada teks asli dan versi tanpa leet; unleet memetakan satu karakter ke satu karakter sehingga posisinya sama.
+	for _, text := range []string{lower, unleet(lower)} {
+		for index, word := range commonPasswords().words {
+			if utf8.RuneCountInString(word) >= 4 {
+				applyMatches(text, word, math.Log10(float64(index)+2), cost)
+			}
+		}
+		for _, input := range userInputs {
+			applyMatches(text, input, math.Log10(2), cost)
+		}
+	}
+
+	total := 0.0
+	for _, value := range cost {
+		total += value
+	}
+	return total
+}
+
+// applyMatches mengganti biaya setiap kemunculan word di text jika lebih murah daripada biaya per karakternya
+func applyMatches(text string, word string, wordCost float64, cost []float64) {
+	for offset := 0; offset < len(text); {
+		index := strings.Index(text[offset:], word)
+		if index < 0 {
+			return
+		}
+		start := utf8.RuneCountInString(text[:offset+index])
+		end := start + utf8.RuneCountInString(word)
+
+		spanCost := 0.0
+		for _, value := range cost[start:end] {
+			spanCost += value
+		}
+		if wordCost < spanCost {
+			cost[start] = wordCost
+			for i := start + 1; i < end; i++ {
+				cost[i] = 0
+			}
+		}
+		offset += index + len(word)
+	}
+}
+
+// isSequential true jika dua karakter berurutan secara abjad / angka atau bersebelahan di keyboard
+func isSequential(prev rune, current rune) bool {
+	if (prev-current == 1 || current-prev == 1) && (unicode.IsLetter(current) || unicode.IsDigit(current)) {
+		return true
+	}
+	for _, row := range keyboardRows {
+		prevIndex, currentIndex := strings.IndexRune(row, prev), strings.IndexRune(row, current)
+		if prevIndex >= 0 && currentIndex >= 0 && (prevIndex-currentIndex == 1 || currentIndex-prevIndex == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// charsetSize ukuran ruang karakter berdasarkan jenis karakter yang dipakai
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, char := range password {
+		switch {
+		case char >= 'a' && char <= 'z':
+			lower = true
+		case char >= 'A' && char <= 'Z':
+			upper = true
+		case char >= '0' && char <= '9':
+			digit = true
+		case char < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			size += class.size
+		}
+	}
+	return max(size, 2)
+}
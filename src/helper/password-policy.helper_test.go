@@ -0,0 +1,121 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := PasswordPolicy{
+		MinLength:     10,
+		MaxLength:     20,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	cases := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		info     []string
+		want     []string
+	}{
+		{"strict accepts", strict, "Tr0ub4dor&3x", nil, nil},
+		{"too short", strict, "Ab1!", nil, []string{"Password minimal harus 10 karakter"}},
+		{"too long", strict, "Ab1!" + strings.Repeat("x", 20), nil, []string{"Password maksimal 20 karakter"}},
+		{"too long skips other rules", strict, strings.Repeat("x", 21), nil, []string{"Password maksimal 20 karakter"}},
+		{"missing classes", strict, "abcdefghijkl", nil, []string{
+			"Password harus mengandung huruf besar",
+			"Password harus mengandung angka",
+			"Password harus mengandung simbol",
+		}},
+		{"length counts characters not bytes", PasswordPolicy{MinLength: 4}, "ééé", nil, []string{"Password minimal harus 4 karakter"}},
+		{"contains username", PasswordPolicy{DisallowPersona: true}, "xBudi2024x", []string{"budi"}, []string{"Password tidak boleh mengandung username, nama, atau email"}},
+		{"contains email local part", PasswordPolicy{DisallowPersona: true}, "santoso-rocks", []string{"b", "", "santoso@example.com"}, []string{"Password tidak boleh mengandung username, nama, atau email"}},
+		{"contains part of full name", PasswordPolicy{DisallowPersona: true}, "iloveanshorie", []string{"ab", "Achyar Anshorie"}, []string{"Password tidak boleh mengandung username, nama, atau email"}},
+		{"short personal tokens ignored", PasswordPolicy{DisallowPersona: true}, "xyzabqq", []string{"ab"}, nil},
+		{"common password", PasswordPolicy{DisallowCommon: true, MinScore: 2}, "password", nil, []string{"Password terlalu umum dan mudah ditebak"}},
+		{"common password with leet", PasswordPolicy{DisallowCommon: true}, "P@ssw0rd", nil, []string{"Password terlalu umum dan mudah ditebak"}},
+		{"weak password", PasswordPolicy{MinScore: 2}, "hunter2", nil, []string{"Password terlalu lemah (skor 1 dari 4, minimal 2)"}},
+		{"score check disabled", PasswordPolicy{}, "hunter2", nil, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.policy.Validate(tc.password, tc.info...)
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("Validate(%q) = %q, want %q", tc.password, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	policy := LoadPasswordPolicy()
+	if policy.MinLength != 8 || policy.MaxLength != 128 || !policy.DisallowPersona || !policy.DisallowCommon || policy.MinScore != 2 ||
+		policy.RequireUpper || policy.RequireLower || policy.RequireDigit || policy.RequireSymbol {
+		t.Errorf("default policy = %+v", policy)
+	}
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_REQUIRE_SYMBOL", "true")
+	t.Setenv("PASSWORD_MIN_SCORE", "0")
+	policy = LoadPasswordPolicy()
+	if policy.MinLength != 12 || !policy.RequireSymbol || policy.MinScore != 0 {
+		t.Errorf("policy from env = %+v", policy)
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	cases := []struct {
+		password string
+		inputs   []string
+		want     int
+	}{
+		{"", nil, 0},
+		{"password", nil, 0},
+		{"qwerty123456", nil, 0},
+		{"aaaaaaaaaaaa", nil, 1},
+		{"abcdefghijkl", nil, 1},
+		{"hunter2", nil, 1},
+		{"Summer2024!", nil, 1},
+		{"dragon2024", nil, 2},
+		{"ilovejakarta", nil, 2},
+		{"Tr0ub4dor&3", nil, 4},
+		{"correcthorsebatterystaple", nil, 4},
+		// Data pribadi dihitung murah: skor turun jika password berisi nama user
+		{"budisantoso77", nil, 4},
+		{"budisantoso77", []string{"budisantoso"}, 0},
+	}
+
+	for _, tc := range cases {
+		if got := PasswordStrength(tc.password, tc.inputs...); got != tc.want {
+			t.Errorf("PasswordStrength(%q, %q) = %d, want %d", tc.password, tc.inputs, got, tc.want)
+		}
+	}
+}
+
+func TestPasswordStrengthIsDeterministic(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		if got := PasswordStrength("ilovejakarta"); got != 2 {
+			t.Fatalf("run %d: PasswordStrength = %d, want 2", i, got)
+		}
+	}
+}
+
+func TestCommonPasswordsList(t *testing.T) {
+	list := commonPasswords()
+	if len(list.words) < 1000 {
+		t.Fatalf("common password list has %d entries, want at least 1000", len(list.words))
+	}
+	if list.ranks["123456"] != 1 {
+		t.Errorf("rank of 123456 = %d, want 1", list.ranks["123456"])
+	}
+	for index, word := range list.words {
+		if list.ranks[word] != index+1 || word != strings.ToLower(word) || word == "" {
+			t.Fatalf("entry %d (%q) is not unique, lowercase and ranked", index, word)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult hasil pencatatan satu request pada rate limiter
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // Waktu sampai satu slot kembali tersedia (request tertua keluar dari window)
+}
+
+// RateLimitStore penyimpanan sliding window rate limit. Hit mencatat request untuk key jika
+// masih di bawah limit; request yang ditolak tidak ikut dihitung.
+type RateLimitStore interface {
+	Hit(key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// rateLimitStore store default yang dipakai middleware rate limit
+var (
+	rateLimitStore   RateLimitStore
+	rateLimitStoreMu sync.Mutex
+)
+
+// SetupRateLimitStore memilih store sesuai RATE_LIMIT_STORE (memory | redis), default memory
+func SetupRateLimitStore() (RateLimitStore, error) {
+	var store RateLimitStore
+	switch GetEnv("RATE_LIMIT_STORE", "memory") {
+	case "memory":
+		store = NewMemoryRateLimitStore()
+	case "redis":
+		options, err := redis.ParseURL(GetEnv("REDIS_URL", "redis://localhost:6379/0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		store = NewRedisRateLimitStore(redis.NewClient(options), GetEnv("RATE_LIMIT_PREFIX", "ratelimit:"))
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+
+	rateLimitStoreMu.Lock()
+	rateLimitStore = store
+	rateLimitStoreMu.Unlock()
+	return store, nil
+}
+
+// GetRateLimitStore mengembalikan store aktif, store memory jika SetupRateLimitStore belum dipanggil
+func GetRateLimitStore() RateLimitStore {
+	rateLimitStoreMu.Lock()
+	defer rateLimitStoreMu.Unlock()
+
+	if rateLimitStore == nil {
+		rateLimitStore = NewMemoryRateLimitStore()
+	}
+	return rateLimitStore
+}
+
+// ParseRateLimit membaca format "jumlah/durasi", misal "10/1m" atau "5/1h"
+func ParseRateLimit(value string) (int, time.Duration, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate limit %q, expected <count>/<duration>", value)
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit count %q", count)
+	}
+	window, err := time.ParseDuration(period)
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate limit window %q", period)
+	}
+	return limit, window, nil
+}
+
+// MemoryRateLimitStore sliding window log di memori, cocok untuk satu instance
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*rateLimitEntry
+}
+
+type rateLimitEntry struct {
+	hits   []time.Time // Terurut dari yang terlama
+	window time.Duration
+}
+
+// NewMemoryRateLimitStore membuat store memori dan membersihkan key kedaluwarsa secara berkala
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{entries: map[string]*rateLimitEntry{}}
+	go func() {
+		for range time.Tick(time.Minute) {
+			store.cleanup()
+		}
+	}()
+	return store
+}
+
+// Hit mencatat request jika jumlah request dalam window masih di bawah limit
+func (s *MemoryRateLimitStore) Hit(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &rateLimitEntry{}
+		s.entries[key] = entry
+	}
+	entry.window = window
+	entry.hits = pruneHits(entry.hits, now.Add(-window))
+
+	result := RateLimitResult{Limit: limit}
+	if len(entry.hits) < limit {
+		entry.hits = append(entry.hits, now)
+		result.Allowed = true
+	}
+	result.Remaining = limit - len(entry.hits)
+	result.ResetAfter = entry.hits[0].Add(window).Sub(now)
+	return result, nil
+}
+
+// cleanup menghapus key yang semua request-nya sudah keluar dari window
+func (s *MemoryRateLimitStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.entries {
+		if entry.hits = pruneHits(entry.hits, now.Add(-entry.window)); len(entry.hits) == 0 {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// pruneHits membuang request yang lebih lama dari batas window
+func pruneHits(hits []time.Time, cutoff time.Time) []time.Time {
+	index := 0
+	for index < len(hits) && !hits[index].After(cutoff) {
+		index++
+	}
+	return hits[index:]
+}
+
+// redisSlidingWindow sliding window log dengan sorted set (skor = waktu request dalam milidetik).
+// Dijalankan sebagai script agar atomik antar instance; request yang ditolak tidak ditambahkan.
+var redisSlidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local oldestScore = now
+if #oldest > 0 then
+	oldestScore = tonumber(oldest[2])
+end
+return {allowed, count, oldestScore}
+`)
+
+// RedisRateLimitStore sliding window log di Redis (atau server yang kompatibel), untuk banyak instance
+type RedisRateLimitStore struct {
+	Client redis.Scripter
+	Prefix string
+}
+
+// NewRedisRateLimitStore membuat store Redis dengan prefix key tertentu
+func NewRedisRateLimitStore(client redis.Scripter, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{Client: client, Prefix: prefix}
+}
+
+// Hit mencatat request jika jumlah request dalam window masih di bawah limit
+func (s *RedisRateLimitStore) Hit(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := time.Now().UnixMilli()
+	values, err := redisSlidingWindow.Run(ctx, s.Client, []string{s.Prefix + key},
+		now, window.Milliseconds(), limit, strconv.FormatInt(now, 10)+"-"+uuid.NewString()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	resetAfter := time.Duration(values[2]+window.Milliseconds()-now) * time.Millisecond
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  limit - int(values[1]),
+		ResetAfter: resetAfter,
+	}, nil
+}
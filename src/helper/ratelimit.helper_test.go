@@ -0,0 +1,158 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitStores store yang diuji dengan kasus yang sama: memory dan Redis (miniredis)
+func rateLimitStores(t *testing.T) map[string]RateLimitStore {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return map[string]RateLimitStore{
+		"memory": NewMemoryRateLimitStore(),
+		"redis":  NewRedisRateLimitStore(client, "test:"),
+	}
+}
+
+func TestRateLimitStoreLimit(t *testing.T) {
+	cases := []struct {
+		name          string
+		limit         int
+		hits          int
+		wantAllowed   int
+		wantRemaining int
+	}{
+		{"below limit", 5, 3, 3, 2},
+		{"exactly limit", 3, 3, 3, 0},
+		{"over limit", 3, 6, 3, 0},
+		{"limit of one", 1, 4, 1, 0},
+	}
+
+	for storeName, store := range rateLimitStores(t) {
+		for _, tc := range cases {
+			t.Run(storeName+"/"+tc.name, func(t *testing.T) {
+				key := "limit:" + tc.name
+				allowed := 0
+				var last RateLimitResult
+				for i := 0; i < tc.hits; i++ {
+					result, err := store.Hit(key, tc.limit, time.Minute)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if result.Allowed {
+						allowed++
+					}
+					if result.Limit != tc.limit {
+						t.Errorf("hit %d: Limit = %d, want %d", i+1, result.Limit, tc.limit)
+					}
+					if result.ResetAfter <= 0 || result.ResetAfter > time.Minute {
+						t.Errorf("hit %d: ResetAfter = %s, want within (0, 1m]", i+1, result.ResetAfter)
+					}
+					last = result
+				}
+				if allowed != tc.wantAllowed {
+					t.Errorf("allowed %d of %d hits, want %d", allowed, tc.hits, tc.wantAllowed)
+				}
+				if last.Remaining != tc.wantRemaining {
+					t.Errorf("Remaining = %d, want %d", last.Remaining, tc.wantRemaining)
+				}
+			})
+		}
+	}
+}
+
+func TestRateLimitStoreKeysAreIndependent(t *testing.T) {
+	for storeName, store := range rateLimitStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			if result, _ := store.Hit("independent:a", 1, time.Minute); !result.Allowed {
+				t.Fatal("first hit for key a was rejected")
+			}
+			if result, _ := store.Hit("independent:a", 1, time.Minute); result.Allowed {
+				t.Fatal("second hit for key a was allowed")
+			}
+			if result, _ := store.Hit("independent:b", 1, time.Minute); !result.Allowed {
+				t.Fatal("key b was limited by key a")
+			}
+		})
+	}
+}
+
+func TestRateLimitStoreWindowSlides(t *testing.T) {
+	const window = 200 * time.Millisecond
+
+	for storeName, store := range rateLimitStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			key := "window:" + storeName
+			for i := 0; i < 2; i++ {
+				if result, err := store.Hit(key, 2, window); err != nil || !result.Allowed {
+					t.Fatalf("hit %d: allowed=%v err=%v, want allowed", i+1, result.Allowed, err)
+				}
+			}
+			if result, _ := store.Hit(key, 2, window); result.Allowed {
+				t.Fatal("hit over the limit was allowed")
+			}
+
+			// Setelah request tertua keluar dari window, slot kembali tersedia
+			time.Sleep(window + 50*time.Millisecond)
+			result, err := store.Hit(key, 2, window)
+			if err != nil || !result.Allowed || result.Remaining != 1 {
+				t.Fatalf("after window: allowed=%v remaining=%d err=%v, want allowed with 1 remaining", result.Allowed, result.Remaining, err)
+			}
+		})
+	}
+}
+
+func TestRedisRateLimitStoreUsesPrefix(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	store := NewRedisRateLimitStore(client, "ratelimit:")
+	if _, err := store.Hit("login_ip:ip:10.0.0.1", 5, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("ratelimit:login_ip:ip:10.0.0.1") {
+		t.Errorf("keys = %v, want ratelimit:login_ip:ip:10.0.0.1", server.Keys())
+	}
+	if ttl := server.TTL("ratelimit:login_ip:ip:10.0.0.1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL = %s, want within (0, 1m]", ttl)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	cases := []struct {
+		value      string
+		wantLimit  int
+		wantWindow time.Duration
+		wantErr    bool
+	}{
+		{"10/1m", 10, time.Minute, false},
+		{" 5/1h ", 5, time.Hour, false},
+		{"120/30s", 120, 30 * time.Second, false},
+		{"10", 0, 0, true},
+		{"0/1m", 0, 0, true},
+		{"-1/1m", 0, 0, true},
+		{"abc/1m", 0, 0, true},
+		{"10/abc", 0, 0, true},
+		{"10/0s", 0, 0, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			limit, window, err := ParseRateLimit(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if limit != tc.wantLimit || window != tc.wantWindow {
+				t.Errorf("got %d/%s, want %d/%s", limit, window, tc.wantLimit, tc.wantWindow)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package helper
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken menyimpan jti access token yang dicabut sebelum masa berlakunya habis
+type RevokedToken struct {
+	Jti       string    `gorm:"type:varchar(36);primaryKey" json:"jti"`
+	UserId    int64     `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"type:datetime;not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"type:datetime;not null;index" json:"created_at"`
+}
+
+// UserTokenRevocation menandai semua token milik user yang diterbitkan sampai RevokedBefore sebagai tidak valid
+type UserTokenRevocation struct {
+	UserId        int64     `gorm:"primaryKey" json:"user_id"`
+	RevokedBefore time.Time `gorm:"type:datetime(6);not null" json:"revoked_before"` // Presisi mikrodetik, tidak dibulatkan ke detik
+	UpdatedAt     time.Time `gorm:"type:datetime;not null;index" json:"updated_at"`
+}
+
+// RevocationStore adalah daftar pencabutan token berbasis database dengan cache di memori
+type RevocationStore struct {
+	DB *gorm.DB
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	users    map[int64]time.Time
+	lastSync time.Time
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+var revocationStore *RevocationStore
+
+// SetupRevocationStore menginisialisasi store pencabutan token dan sinkronisasi berkala antar instance
+func SetupRevocationStore(db *gorm.DB) *RevocationStore {
+	store := &RevocationStore{
+		DB:     db,
+		tokens: make(map[string]time.Time),
+		users:  make(map[int64]time.Time),
+		stop:   make(chan struct{}),
+	}
+	if err := store.Sync(); err != nil {
+		log.Println("Gagal memuat daftar token yang dicabut:", err)
+	}
+
+	interval := time.Duration(GetEnvInt("REVOCATION_SYNC_INTERVAL", 30)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.Sync(); err != nil {
+					log.Println("Gagal sinkronisasi daftar token yang dicabut:", err)
+				}
+			case <-store.stop:
+				return
+			}
+		}
+	}()
+
+	revocationStore = store
+	return store
+}
+
+// Close menghentikan sinkronisasi berkala yang dimulai SetupRevocationStore, aman dipanggil berulang
+func (s *RevocationStore) Close() {
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
+}
+
+// Sync memuat pencabutan baru dari database ke cache dan membuang entri yang sudah kedaluwarsa
+func (s *RevocationStore) Sync() error {
+	now := time.Now()
+
+	s.mu.RLock()
+	since := s.lastSync
+	s.mu.RUnlock()
+
+	var tokens []RevokedToken
+	if err := s.DB.Where("expires_at > ? AND created_at >= ?", now, since).Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	var users []UserTokenRevocation
+	if err := s.DB.Where("updated_at >= ?", since).Find(&users).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, token := range tokens {
+		s.tokens[token.Jti] = token.ExpiresAt
+	}
+	for _, revocation := range users {
+		s.users[revocation.UserId] = revocation.RevokedBefore
+	}
+	for jti, expiresAt := range s.tokens {
+		if now.After(expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	// Mundurkan sedikit agar baris yang ditulis bersamaan dengan sync tidak terlewat
+	s.lastSync = now.Add(-time.Second)
+	return nil
+}
+
+// RevokeToken mencabut satu access token berdasarkan jti sampai waktu kedaluwarsanya
+func (s *RevocationStore) RevokeToken(jti string, userID int64, expiresAt time.Time) error {
+	revoked := RevokedToken{
+		Jti:       jti,
+		UserId:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.DB.Save(&revoked).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = expiresAt
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeUser mencabut semua token user yang diterbitkan sampai saat ini
+func (s *RevocationStore) RevokeUser(userID int64) error {
+	now := time.Now()
+	revocation := UserTokenRevocation{
+		UserId:        userID,
+		RevokedBefore: now.Truncate(time.Microsecond), // Sama dengan presisi kolom dan claim iat_us
+		UpdatedAt:     now,
+	}
+	if err := s.DB.Save(&revocation).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = revocation.RevokedBefore
+	s.mu.Unlock()
+	return nil
+}
+
+// IsRevoked mengecek apakah token dengan jti / waktu terbit tertentu sudah dicabut
+func (s *RevocationStore) IsRevoked(jti string, userID int64, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if expiresAt, ok := s.tokens[jti]; ok && time.Now().Before(expiresAt) {
+		return true
+	}
+	// issuedAt berasal dari helper.TokenIssuedAt (presisi mikrodetik), token yang terbit setelah pencabutan tetap berlaku
+	if revokedBefore, ok := s.users[userID]; ok && !issuedAt.After(revokedBefore) {
+		return true
+	}
+	return false
+}
+
+// RevokeToken mencabut access token melalui store default
+func RevokeToken(jti string, userID int64, expiresAt time.Time) error {
+	if revocationStore == nil {
+		return nil
+	}
+	return revocationStore.RevokeToken(jti, userID, expiresAt)
+}
+
+// RevokeUserTokens mencabut semua access token milik user melalui store default
+func RevokeUserTokens(userID int64) error {
+	if revocationStore == nil {
+		return nil
+	}
+	return revocationStore.RevokeUser(userID)
+}
+
+// IsTokenRevoked mengecek pencabutan token melalui store default
+func IsTokenRevoked(jti string, userID int64, issuedAt time.Time) bool {
+	if revocationStore == nil {
+		return false
+	}
+	return revocationStore.IsRevoked(jti, userID, issuedAt)
+}
@@ -0,0 +1,194 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"github.com/achyar10/go-auth/src/testutil"
+)
+
+// newRevocationStore store tanpa goroutine sinkronisasi berkala milik SetupRevocationStore
+func newRevocationStore(db *gorm.DB) *RevocationStore {
+	return &RevocationStore{
+		DB:     db,
+		tokens: make(map[string]time.Time),
+		users:  make(map[int64]time.Time),
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	store := newRevocationStore(testutil.NewDB(t, &RevokedToken{}, &UserTokenRevocation{}))
+	issuedAt := time.Now().Add(-time.Minute)
+
+	if err := store.RevokeToken("jti-1", 1, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if !store.IsRevoked("jti-1", 1, issuedAt) {
+		t.Error("revoked jti is not reported as revoked")
+	}
+	if store.IsRevoked("jti-2", 1, issuedAt) {
+		t.Error("other jti of the same user is reported as revoked")
+	}
+
+	// Entri yang sudah lewat masa berlakunya tidak perlu dicek lagi
+	if err := store.RevokeToken("jti-expired", 1, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if store.IsRevoked("jti-expired", 1, issuedAt) {
+		t.Error("expired revocation entry still applies")
+	}
+}
+
+func TestRevokeUserCutoff(t *testing.T) {
+	store := newRevocationStore(testutil.NewDB(t, &RevokedToken{}, &UserTokenRevocation{}))
+
+	if err := store.RevokeUser(7); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	cutoff := store.users[7]
+
+	cases := []struct {
+		name     string
+		userID   int64
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued earlier", 7, cutoff.Add(-time.Hour), true},
+		{"issued a microsecond before cutoff", 7, cutoff.Add(-time.Microsecond), true},
+		{"issued exactly at cutoff", 7, cutoff, true},
+		{"issued a microsecond after cutoff", 7, cutoff.Add(time.Microsecond), false},
+		{"issued in the next second", 7, cutoff.Truncate(time.Second).Add(time.Second), false},
+		{"other user", 8, cutoff.Add(-time.Hour), false},
+	}
+	for _, tc := range cases {
+		if got := store.IsRevoked("jti", tc.userID, tc.issuedAt); got != tc.want {
+			t.Errorf("%s: IsRevoked = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTokenIssuedRightAfterRevokeUserIsValid(t *testing.T) {
+	store := newRevocationStore(testutil.NewDB(t, &RevokedToken{}, &UserTokenRevocation{}))
+
+	oldToken, err := GenerateJWT(7, "budi", "Budi", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeUser(7); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	// Login ulang di detik yang sama dengan logout-all
+	newToken, err := GenerateJWT(7, "budi", "Budi", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuedAt := func(tokenString string) time.Time {
+		token, err := ValidateJWT(tokenString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued, ok := TokenIssuedAt(token.Claims.(jwt.MapClaims))
+		if !ok {
+			t.Fatal("token has no issued-at claim")
+		}
+		return issued
+	}
+
+	if !store.IsRevoked("old", 7, issuedAt(oldToken)) {
+		t.Error("token issued before RevokeUser is still valid")
+	}
+	if store.IsRevoked("new", 7, issuedAt(newToken)) {
+		t.Error("token issued right after RevokeUser is revoked")
+	}
+}
+
+func TestTokenIssuedAt(t *testing.T) {
+	issued := time.Date(2026, 1, 2, 3, 4, 5, 678901000, time.UTC)
+
+	precise, ok := TokenIssuedAt(jwt.MapClaims{"iat": float64(issued.Unix()), "iat_us": float64(issued.UnixMicro())})
+	if !ok || !precise.Equal(issued) {
+		t.Errorf("with iat_us: got %v, want %v", precise, issued)
+	}
+
+	// Token lama hanya punya iat (detik)
+	legacy, ok := TokenIssuedAt(jwt.MapClaims{"iat": float64(issued.Unix())})
+	if !ok || !legacy.Equal(issued.Truncate(time.Second)) {
+		t.Errorf("without iat_us: got %v, want %v", legacy, issued.Truncate(time.Second))
+	}
+
+	if _, ok := TokenIssuedAt(jwt.MapClaims{}); ok {
+		t.Error("claims without iat reported as issued")
+	}
+}
+
+func TestRevocationSyncAcrossInstances(t *testing.T) {
+	db := testutil.NewDB(t, &RevokedToken{}, &UserTokenRevocation{})
+	first := newRevocationStore(db)
+	second := newRevocationStore(db)
+	if err := second.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	issuedAt := time.Now().Add(-time.Minute)
+	if err := first.RevokeToken("jti-1", 1, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := first.RevokeUser(2); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	if second.IsRevoked("jti-1", 1, issuedAt) || second.IsRevoked("jti-x", 2, issuedAt) {
+		t.Fatal("second instance sees revocations before sync")
+	}
+
+	if err := second.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if !second.IsRevoked("jti-1", 1, issuedAt) {
+		t.Error("revoked jti not picked up by sync")
+	}
+	if !second.IsRevoked("jti-x", 2, issuedAt) {
+		t.Error("user revocation not picked up by sync")
+	}
+
+	// Presisi mikrodetik RevokedBefore harus bertahan lewat database
+	if !second.users[2].Equal(first.users[2]) {
+		t.Errorf("synced cutoff = %v, want %v", second.users[2], first.users[2])
+	}
+}
+
+func TestRevocationWithoutStore(t *testing.T) {
+	previous := revocationStore
+	revocationStore = nil
+	t.Cleanup(func() { revocationStore = previous })
+
+	if err := RevokeToken("jti", 1, time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("RevokeToken without store: %v", err)
+	}
+	if err := RevokeUserTokens(1); err != nil {
+		t.Errorf("RevokeUserTokens without store: %v", err)
+	}
+	if IsTokenRevoked("jti", 1, time.Now()) {
+		t.Error("IsTokenRevoked without store reports revoked")
+	}
+}
+
+func TestRevocationStoreClose(t *testing.T) {
+	previous := revocationStore
+	t.Cleanup(func() { revocationStore = previous })
+
+	store := SetupRevocationStore(testutil.NewDB(t, &RevokedToken{}, &UserTokenRevocation{}))
+	store.Close()
+	store.Close()
+	select {
+	case <-store.stop:
+	default:
+		t.Error("sync goroutine not signalled to stop")
+	}
+
+	// Store tanpa goroutine sinkronisasi juga boleh ditutup
+	newRevocationStore(store.DB).Close()
+}
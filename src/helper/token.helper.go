@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix awalan personal access token agar mudah dikenali (misal oleh secret scanner)
+const PersonalAccessTokenPrefix = "pat_"
+
+// GenerateRandomToken membuat token opaque acak (base64 url-safe) dengan panjang byte tertentu
+func GenerateRandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken meng-hash token opaque dengan SHA-256 agar bisa dicari di database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// recoveryCodeAlphabet tanpa karakter yang mirip (0/o, 1/l/i)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes membuat sejumlah kode pemulihan MFA dengan format xxxx-xxxx-xxxx-xxxx (sekitar 79 bit acak),
+// cukup kuat untuk disimpan sebagai hash SHA-256 biasa (HashToken) tanpa key stretching
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := 0; i < count; i++ {
+		var code strings.Builder
+		for j := 0; j < 16; j++ {
+			if j > 0 && j%4 == 0 {
+				code.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		codes = append(codes, code.String())
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode menyeragamkan input kode pemulihan (huruf kecil, tanpa spasi)
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
@@ -1,6 +1,8 @@
 package helper
 
 import (
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -12,12 +14,16 @@ func GetValidationErrors(err error) []string {
 			var errorMessage string
 
 			switch fieldErr.Tag() {
-			case "required":
+			case "required", "required_unless":
 				errorMessage = fieldErr.Field() + " harus diisi"
+			case "excluded_if":
+				errorMessage = fieldErr.Field() + " tidak boleh diisi"
 			case "min":
 				errorMessage = fieldErr.Field() + " minimal harus " + fieldErr.Param() + " karakter"
 			case "max":
 				errorMessage = fieldErr.Field() + " maksimal " + fieldErr.Param() + " karakter"
+			case "email":
+				errorMessage = fieldErr.Field() + " harus berupa alamat email yang valid"
 			case "oneof":
 				errorMessage = fieldErr.Field() + " harus salah satu dari " + fieldErr.Param()
 			default:
@@ -29,3 +35,8 @@ func GetValidationErrors(err error) []string {
 	}
 	return errors
 }
+
+// NormalizeEmail menormalisasi alamat email (tanpa spasi, huruf kecil)
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -8,8 +8,34 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware untuk melindungi route dengan JWT
+// APIKeyIdentity data pemilik personal access token yang sudah diverifikasi
+type APIKeyIdentity struct {
+	TokenId  int64
+	UserId   int64
+	Username string
+	Fullname string
+	Role     string
+	Scope    string
+}
+
+// APIKeyAuthenticator memverifikasi personal access token, diisi oleh package user saat setup route
+// (middleware tidak boleh mengimpor package app). Nil berarti personal access token tidak didukung.
+var APIKeyAuthenticator func(token string) (*APIKeyIdentity, error)
+
+// AuthMiddleware untuk melindungi route first-party dengan JWT atau personal access token.
+// Access token user yang didelegasikan ke client OAuth (claim delegated) ditolak, karena scope-nya tidak berlaku di sini.
 func AuthMiddleware(ctx *fiber.Ctx) error {
+	return authenticate(ctx, false)
+}
+
+// OAuthMiddleware seperti AuthMiddleware tetapi juga menerima access token user yang didelegasikan ke client OAuth,
+// untuk route milik OAuth / OIDC (misal userinfo) yang dibatasi lewat RequireScope
+func OAuthMiddleware(ctx *fiber.Ctx) error {
+	return authenticate(ctx, true)
+}
+
+// authenticate memverifikasi token dan mengisi context; allowClients menentukan apakah token delegasi client OAuth diterima
+func authenticate(ctx *fiber.Ctx, allowClients bool) error {
 	// Ambil token dari header Authorization
 	authHeader := ctx.Get("Authorization")
 	if authHeader == "" {
@@ -30,6 +56,11 @@ func AuthMiddleware(ctx *fiber.Ctx) error {
 
 	tokenString := tokenParts[1]
 
+	// Personal access token dikenali dari prefix-nya, bukan JWT
+	if strings.HasPrefix(tokenString, helper.PersonalAccessTokenPrefix) && APIKeyAuthenticator != nil {
+		return apiKeyAuth(ctx, tokenString)
+	}
+
 	// Validasi token JWT
 	token, err := helper.ValidateJWT(tokenString)
 	if err != nil || !token.Valid {
@@ -48,11 +79,96 @@ func AuthMiddleware(ctx *fiber.Ctx) error {
 		})
 	}
 
+	// Hanya access token yang boleh dipakai (token tantangan MFA dsb. ditolak)
+	if use, ok := claims["token_use"]; ok && use != "access" {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  fiber.StatusUnauthorized,
+			"message": "Invalid token type",
+		})
+	}
+
+	// Tolak token yang sudah dicabut (logout atau pencabutan semua sesi user)
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(float64)
+	issuedAt, _ := helper.TokenIssuedAt(claims)
+	// Token impersonasi juga ikut dicabut jika sesi admin yang memulainya dicabut
+	act, impersonating := claims["act"].(map[string]interface{})
+	actorID, _ := act["user_id"].(float64)
+	if helper.IsTokenRevoked(jti, int64(userID), issuedAt) || (impersonating && helper.IsTokenRevoked("", int64(actorID), issuedAt)) {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  fiber.StatusUnauthorized,
+			"message": "Token has been revoked",
+		})
+	}
+
+	// Token user yang didelegasikan ke client OAuth hanya berlaku untuk scope-nya, bukan route first-party.
+	// Token client_credentials dan token service account tetap diterima.
+	if delegated, _ := claims["delegated"].(bool); delegated && !allowClients {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  fiber.StatusForbidden,
+			"message": "OAuth access tokens cannot be used on this endpoint",
+		})
+	}
+
 	// Simpan data user ke context
+	ctx.Locals("jti", jti)
+	ctx.Locals("exp", claims["exp"])
+	ctx.Locals("client_id", claims["client_id"]) // Terisi jika token diterbitkan untuk client OAuth
+	ctx.Locals("scope", claims["scope"])
+
+	// Token client_credentials tidak mewakili user, data user tidak diisi
+	if claims["subject_type"] == "client" {
+		ctx.Locals("subject_type", "client")
+		return ctx.Next()
+	}
+
+	ctx.Locals("subject_type", "user")
 	ctx.Locals("user_id", claims["user_id"])
 	ctx.Locals("username", claims["username"])
 	ctx.Locals("fullname", claims["fullname"])
 	ctx.Locals("role", claims["role"])
 
+	// Admin yang sedang impersonasi: user_id berisi user target, actor_* berisi admin yang sebenarnya
+	ctx.Locals("impersonating", impersonating)
+	if impersonating {
+		ctx.Locals("actor_id", act["user_id"])
+		ctx.Locals("actor_username", act["username"])
+	}
+
+	return ctx.Next()
+}
+
+// apiKeyAuth memverifikasi personal access token dan mengisi context seperti token user biasa
+func apiKeyAuth(ctx *fiber.Ctx, tokenString string) error {
+	identity, err := APIKeyAuthenticator(tokenString)
+	if err != nil || identity == nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"status":  fiber.StatusUnauthorized,
+			"message": "Invalid or expired token",
+		})
+	}
+
+	// Token tanpa scope "write" hanya boleh dipakai untuk request yang tidak mengubah data
+	if !strings.Contains(" "+identity.Scope+" ", " write ") {
+		switch ctx.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		default:
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"status":  fiber.StatusForbidden,
+				"message": "Insufficient scope, required: write",
+			})
+		}
+	}
+
+	// user_id disimpan sebagai float64 agar sama dengan claim JWT
+	ctx.Locals("token_type", "api_key")
+	ctx.Locals("api_key_id", identity.TokenId)
+	ctx.Locals("scope", identity.Scope)
+	ctx.Locals("subject_type", "user")
+	ctx.Locals("user_id", float64(identity.UserId))
+	ctx.Locals("username", identity.Username)
+	ctx.Locals("fullname", identity.Fullname)
+	ctx.Locals("role", identity.Role)
+
 	return ctx.Next()
 }
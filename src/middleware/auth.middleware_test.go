@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/achyar10/go-auth/src/helper"
+)
+
+// authTestApp route first-party (AuthMiddleware) dan route OAuth (OAuthMiddleware), masing-masing dengan
+// varian yang mewajibkan token user
+func authTestApp() *fiber.App {
+	app := fiber.New()
+	ok := func(ctx *fiber.Ctx) error { return ctx.SendStatus(fiber.StatusOK) }
+	app.Get("/first-party", AuthMiddleware, ok)
+	app.Get("/first-party/user", AuthMiddleware, RequireUser, ok)
+	app.Get("/oauth", OAuthMiddleware, ok)
+	return app
+}
+
+func requestWithToken(t *testing.T, app *fiber.App, path string, token string) int {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+func TestAuthMiddlewareTokenKinds(t *testing.T) {
+	mustToken := func(token string, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	userToken := mustToken(helper.GenerateJWT(1, "budi", "Budi", "user"))
+	clientToken := mustToken(helper.GenerateClientJWT("reporting", "reports:read", time.Minute))
+	serviceAccountToken := mustToken(helper.GenerateScopedJWT(2, "svc-reporting", "", "user", "reporting", "reports:read"))
+	delegatedToken := mustToken(helper.GenerateDelegatedJWT(1, "budi", "Budi", "user", "third-party", "openid"))
+
+	cases := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"user token on first-party route", "/first-party", userToken, http.StatusOK},
+		{"client_credentials token on first-party route", "/first-party", clientToken, http.StatusOK},
+		{"client_credentials token on user-only route", "/first-party/user", clientToken, http.StatusForbidden},
+		{"service account token on first-party route", "/first-party", serviceAccountToken, http.StatusOK},
+		{"service account token on user-only route", "/first-party/user", serviceAccountToken, http.StatusOK},
+		{"delegated token on first-party route", "/first-party", delegatedToken, http.StatusForbidden},
+		{"delegated token on OAuth route", "/oauth", delegatedToken, http.StatusOK},
+		{"invalid token", "/first-party", "not-a-jwt", http.StatusUnauthorized},
+	}
+
+	app := authTestApp()
+	for _, tc := range cases {
+		if got := requestWithToken(t, app, tc.path, tc.token); got != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
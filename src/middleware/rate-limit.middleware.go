@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/achyar10/go-auth/src/helper"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitKeyFunc menghasilkan key rate limit dari request; string kosong berarti limit tidak berlaku untuk request ini
+type RateLimitKeyFunc func(ctx *fiber.Ctx) string
+
+// RateLimitByIP key berdasarkan alamat IP
+func RateLimitByIP(ctx *fiber.Ctx) string {
+	return "ip:" + ctx.IP()
+}
+
+// RateLimitByUsername key berdasarkan username dari Basic Auth / token (Locals) atau body request
+func RateLimitByUsername(ctx *fiber.Ctx) string {
+	username, _ := ctx.Locals("username").(string)
+	if username == "" {
+		var body struct {
+			Username string `json:"username" form:"username"`
+		}
+		_ = ctx.BodyParser(&body)
+		username = body.Username
+	}
+
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" {
+		return ""
+	}
+	return "username:" + username
+}
+
+// RateLimitByClient key berdasarkan client OAuth atau user pemilik token (dipasang setelah AuthMiddleware),
+// atau client_id dari header Basic / body form untuk endpoint yang mengautentikasi client sendiri
+func RateLimitByClient(ctx *fiber.Ctx) string {
+	if clientID, _ := ctx.Locals("client_id").(string); clientID != "" {
+		return "client:" + clientID
+	}
+	if userID, ok := ctx.Locals("user_id").(float64); ok {
+		return "user:" + strconv.FormatInt(int64(userID), 10)
+	}
+	if header := ctx.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Basic ") {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic ")); err == nil {
+			clientID, _, _ := strings.Cut(string(decoded), ":")
+			if clientID, err = url.QueryUnescape(clientID); err == nil && clientID != "" {
+				return "client:" + clientID
+			}
+		}
+	}
+	if clientID := ctx.FormValue("client_id"); clientID != "" {
+		return "client:" + clientID
+	}
+	return ""
+}
+
+// rateLimitEnvAliases nama env lama yang masih dibaca jika env baru kosong (limit sudah diganti nama)
+var rateLimitEnvAliases = map[string]string{
+	"RATE_LIMIT_TOKEN_REFRESH_IP":     "RATE_LIMIT_REFRESH_IP",
+	"RATE_LIMIT_TOKEN_REFRESH_CLIENT": "RATE_LIMIT_REFRESH_CLIENT",
+}
+
+// rateLimitConfig membaca batas dari env, lalu nama env lama, lalu fallback
+func rateLimitConfig(envKey string, fallback string) string {
+	if value := os.Getenv(envKey); value != "" {
+		return value
+	}
+	if alias, ok := rateLimitEnvAliases[envKey]; ok {
+		if value := os.Getenv(alias); value != "" {
+			log.Printf("%s sudah deprecated, gunakan %s", alias, envKey)
+			return value
+		}
+	}
+	return fallback
+}
+
+// RateLimit membatasi request per key dengan sliding window. Batas dibaca dari env RATE_LIMIT_<NAME>
+// dengan format "jumlah/durasi" (misal "10/1m"), fallback dipakai jika env kosong.
+// Nonaktifkan semua limit dengan RATE_LIMIT_ENABLED=false.
+func RateLimit(name string, fallback string, keyFunc RateLimitKeyFunc) fiber.Handler {
+	envKey := "RATE_LIMIT_" + strings.ToUpper(name)
+	limit, window, err := helper.ParseRateLimit(rateLimitConfig(envKey, fallback))
+	if err != nil {
+		log.Fatalf("Konfigurasi %s tidak valid: %v", envKey, err)
+	}
+	enabled := helper.GetEnvBool("RATE_LIMIT_ENABLED", true)
+
+	return func(ctx *fiber.Ctx) error {
+		if !enabled {
+			return ctx.Next()
+		}
+
+		key := keyFunc(ctx)
+		if key == "" {
+			return ctx.Next()
+		}
+		key = strings.ToLower(name) + ":" + key
+
+		result, err := helper.GetRateLimitStore().Hit(key, limit, window)
+		if err != nil {
+			// Store bermasalah: request tetap dilayani (fail open) agar login tidak ikut mati
+			log.Printf("Rate limit store gagal untuk key %s: %v", key, err)
+			return ctx.Next()
+		}
+
+		resetSeconds := int(math.Max(1, math.Ceil(result.ResetAfter.Seconds())))
+		setRateLimitHeaders(ctx, result, resetSeconds)
+
+		if !result.Allowed {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(resetSeconds))
+			return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"status":  fiber.StatusTooManyRequests,
+				"message": "Too many requests, please try again later",
+			})
+		}
+		return ctx.Next()
+	}
+}
+
+// setRateLimitHeaders mengisi header RateLimit-* (draft IETF RateLimit header fields).
+// Jika beberapa limit dipasang di satu route, yang ditampilkan adalah limit dengan sisa paling sedikit.
+func setRateLimitHeaders(ctx *fiber.Ctx, result helper.RateLimitResult, resetSeconds int) {
+	if current, ok := ctx.Locals("ratelimit_remaining").(int); ok && current <= result.Remaining {
+		return
+	}
+	ctx.Locals("ratelimit_remaining", result.Remaining)
+
+	ctx.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Set("RateLimit-Reset", strconv.Itoa(resetSeconds))
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// rateLimitedApp satu route dengan rate limit; key diambil dari header X-Key (kosong = tanpa limit)
+func rateLimitedApp(name string, fallback string) *fiber.App {
+	app := fiber.New()
+	app.Get("/", RateLimit(name, fallback, func(ctx *fiber.Ctx) string {
+		return ctx.Get("X-Key")
+	}), func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, key string) *http.Response {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		request.Header.Set("X-Key", key)
+	}
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestRateLimitRejectsOverLimit(t *testing.T) {
+	app := rateLimitedApp("test_over_limit", "2/1m")
+
+	cases := []struct {
+		wantStatus    int
+		wantRemaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+	for i, tc := range cases {
+		response := get(t, app, "client-a")
+		if response.StatusCode != tc.wantStatus {
+			t.Fatalf("request %d: status %d, want %d", i+1, response.StatusCode, tc.wantStatus)
+		}
+		if got := response.Header.Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: RateLimit-Limit = %q, want 2", i+1, got)
+		}
+		if got := response.Header.Get("RateLimit-Remaining"); got != tc.wantRemaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %s", i+1, got, tc.wantRemaining)
+		}
+		if tc.wantStatus == http.StatusTooManyRequests && response.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Errorf("request %d: missing Retry-After", i+1)
+		}
+	}
+
+	// Key lain punya bucket sendiri
+	if response := get(t, app, "client-b"); response.StatusCode != http.StatusOK {
+		t.Errorf("other key: status %d, want 200", response.StatusCode)
+	}
+}
+
+func TestRateLimitSkipsEmptyKey(t *testing.T) {
+	app := rateLimitedApp("test_empty_key", "1/1m")
+	for i := 0; i < 3; i++ {
+		response := get(t, app, "")
+		if response.StatusCode != http.StatusOK || response.Header.Get("RateLimit-Limit") != "" {
+			t.Fatalf("request %d without key: status %d, RateLimit-Limit %q, want 200 without headers",
+				i+1, response.StatusCode, response.Header.Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestRateLimitEnvOverride(t *testing.T) {
+	t.Setenv("RATE_LIMIT_TEST_ENV_OVERRIDE", "1/1m")
+	app := rateLimitedApp("test_env_override", "100/1m")
+
+	if response := get(t, app, "client-a"); response.StatusCode != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", response.StatusCode)
+	}
+	if response := get(t, app, "client-a"); response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429 (limit from env)", response.StatusCode)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	app := rateLimitedApp("test_disabled", "1/1m")
+
+	for i := 0; i < 3; i++ {
+		if response := get(t, app, "client-a"); response.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200 when disabled", i+1, response.StatusCode)
+		}
+	}
+}
+
+func TestRateLimitLegacyEnvName(t *testing.T) {
+	t.Setenv("RATE_LIMIT_REFRESH_IP", "1/1m")
+	app := rateLimitedApp("token_refresh_ip", "100/1m")
+
+	if response := get(t, app, "client-a"); response.StatusCode != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", response.StatusCode)
+	}
+	if response := get(t, app, "client-a"); response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429 (limit from legacy env)", response.StatusCode)
+	}
+
+	// Nama env baru lebih diutamakan
+	t.Setenv("RATE_LIMIT_TOKEN_REFRESH_IP", "100/1m")
+	if got := rateLimitConfig("RATE_LIMIT_TOKEN_REFRESH_IP", "5/1m"); got != "100/1m" {
+		t.Errorf("config = %q, want value of the new env name", got)
+	}
+}
+
+func TestRateLimitByClient(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(RateLimitByClient(ctx))
+	})
+
+	cases := []struct {
+		name          string
+		authorization string
+		body          string
+		want          string
+	}{
+		{"basic auth", "Basic " + base64.StdEncoding.EncodeToString([]byte("my%20app:secret")), "", "client:my app"},
+		{"form client_id", "", "client_id=reporting&grant_type=client_credentials", "client:reporting"},
+		{"no client", "", "grant_type=client_credentials", ""},
+	}
+	for _, tc := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tc.authorization != "" {
+			request.Header.Set("Authorization", tc.authorization)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(key) != tc.want {
+			t.Errorf("%s: key = %q, want %q", tc.name, key, tc.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// RoleMiddleware membatasi akses route hanya untuk role tertentu (dipasang setelah AuthMiddleware)
+func RoleMiddleware(roles ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		role, _ := ctx.Locals("role").(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				return ctx.Next()
+			}
+		}
+
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  fiber.StatusForbidden,
+			"message": "You do not have permission to access this resource",
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireUser menolak token client_credentials pada route yang membutuhkan user (dipasang setelah AuthMiddleware)
+func RequireUser(ctx *fiber.Ctx) error {
+	if ctx.Locals("subject_type") != "user" {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  fiber.StatusForbidden,
+			"message": "This endpoint requires a user token",
+		})
+	}
+	return ctx.Next()
+}
+
+// ForbidImpersonation menolak aksi sensitif (kredensial, MFA, sesi) saat admin sedang impersonasi (dipasang setelah AuthMiddleware)
+func ForbidImpersonation(ctx *fiber.Ctx) error {
+	if impersonating, _ := ctx.Locals("impersonating").(bool); impersonating {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  fiber.StatusForbidden,
+			"message": "This action is not allowed while impersonating a user",
+		})
+	}
+	return ctx.Next()
+}
+
+// RequireScope membatasi route hanya untuk token yang memiliki semua scope tertentu (dipasang setelah AuthMiddleware)
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		scope, _ := ctx.Locals("scope").(string)
+		granted := strings.Fields(scope)
+
+		for _, required := range scopes {
+			found := false
+			for _, s := range granted {
+				if s == required {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"status":  fiber.StatusForbidden,
+					"message": "Insufficient scope, required: " + strings.Join(scopes, " "),
+				})
+			}
+		}
+		return ctx.Next()
+	}
+}
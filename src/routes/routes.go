@@ -5,6 +5,9 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/achyar10/go-auth/src/app/auth"
+	"github.com/achyar10/go-auth/src/app/federation"
+	"github.com/achyar10/go-auth/src/app/oauth"
+	"github.com/achyar10/go-auth/src/app/passkey"
 	"github.com/achyar10/go-auth/src/app/user"
 )
 
@@ -14,6 +17,15 @@ func SetupRoutes(app *fiber.App, db *gorm.DB) {
 	// Auth
 	auth.SetupAuthRoutes(app, db)
 
+	// WebAuthn / passkey
+	passkey.SetupRoutes(app, db)
+
+	// Login federasi (OIDC upstream)
+	federation.SetupRoutes(app, db)
+
+	// OAuth2 authorization server
+	oauth.SetupRoutes(app, db)
+
 	// User
 	user.SetupRoutes(app, db)
 }
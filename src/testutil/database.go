@@ -0,0 +1,88 @@
+// Package testutil helper bersama untuk test: database SQLite in-memory pengganti MySQL
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// usersTableSQL tabel users versi SQLite; tag model user.User memakai tipe khusus MySQL (enum, ON UPDATE)
+// sehingga tidak bisa di-AutoMigrate. Kolom harus mengikuti user.User.
+const usersTableSQL = `CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL,
+	password TEXT,
+	fullname TEXT,
+	email TEXT UNIQUE,
+	email_verified_at DATETIME,
+	role TEXT DEFAULT 'user',
+	auth_source TEXT NOT NULL DEFAULT 'local',
+	kind TEXT NOT NULL DEFAULT 'human',
+	owner_id INTEGER,
+	"groups" TEXT,
+	is_active NUMERIC DEFAULT true,
+	totp_secret TEXT,
+	totp_last_step INTEGER,
+	mfa_enabled NUMERIC DEFAULT false,
+	failed_logins INTEGER NOT NULL DEFAULT 0,
+	last_failed_at DATETIME,
+	locked_until DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// dialector SQLite yang membuang presisi pada tipe datetime(n): driver hanya mengenali
+// kolom "datetime" persis sebagai waktu, selain itu nilainya dibaca sebagai string
+type dialector struct {
+	sqlite.Dialector
+}
+
+// DataTypeOf tipe kolom untuk field, datetime(6) menjadi datetime
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	dataType := d.Dialector.DataTypeOf(field)
+	if strings.HasPrefix(strings.ToLower(dataType), "datetime(") {
+		return "datetime"
+	}
+	return dataType
+}
+
+// Migrator sama dengan migrator SQLite tetapi memakai DataTypeOf milik dialector ini
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqlite.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// NewDB membuka database SQLite in-memory berisi tabel users dan tabel untuk models (AutoMigrate).
+// Koneksi ditutup otomatis saat test selesai.
+func NewDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(dialector{Dialector: sqlite.Dialector{DSN: ":memory:"}}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// Satu koneksi saja: setiap koneksi :memory: adalah database terpisah
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Exec(usersTableSQL).Error; err != nil {
+		t.Fatalf("create users: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
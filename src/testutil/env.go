@@ -0,0 +1,14 @@
+package testutil
+
+import "os"
+
+// SetSecrets mengisi JWT_SECRET dan ENCRYPTION_KEY khusus test jika belum diisi, karena aplikasi
+// tidak punya nilai default untuk keduanya. Dipanggil dari TestMain sebelum kunci pertama kali dimuat.
+func SetSecrets() {
+	if os.Getenv("JWT_SECRET") == "" {
+		os.Setenv("JWT_SECRET", "test-jwt-secret")
+	}
+	if os.Getenv("ENCRYPTION_KEY") == "" {
+		os.Setenv("ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=") // 32 byte
+	}
+}